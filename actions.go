@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Action step operations
+const (
+	StepStart       = "start"
+	StepStop        = "stop"
+	StepRestart     = "restart"
+	StepWaitHealthy = "wait-healthy"
+	StepDelay       = "delay"
+)
+
+// defaultWaitHealthyTimeout bounds a wait-healthy step with no duration set
+const defaultWaitHealthyTimeout = 30 * time.Second
+
+// ActionStep is a single operation in an action macro. Duration is how long
+// a delay step sleeps, or the timeout of a wait-healthy step.
+type ActionStep struct {
+	Op       string   `json:"op"`
+	App      string   `json:"app,omitempty"`
+	Duration Duration `json:"duration,omitempty"`
+}
+
+// ActionConfig defines a named, ordered sequence of steps
+type ActionConfig struct {
+	Name  string       `json:"name"`
+	Steps []ActionStep `json:"steps"`
+}
+
+// StepResult reports how a single action step went
+type StepResult struct {
+	Step       ActionStep `json:"step"`
+	OK         bool       `json:"ok"`
+	Error      string     `json:"error,omitempty"`
+	DurationMS int64      `json:"duration_ms"`
+}
+
+// SetActions replaces the configured action macros
+func (m *Manager) SetActions(actions []ActionConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.actions = make(map[string]ActionConfig, len(actions))
+	for _, a := range actions {
+		m.actions[a.Name] = a
+	}
+}
+
+// restartExitTimeout bounds how long a restart waits for the old process to
+// exit before giving up
+const restartExitTimeout = 10 * time.Second
+
+// RestartApp stops an app if it is running, waits for its process to exit
+// and starts it again, announcing the restart with the given reason. The app
+// counts as Starting throughout, so concurrent starts and restarts are
+// refused instead of racing it.
+func (m *Manager) RestartApp(appName, reason string) error {
+	m.mu.Lock()
+	app, ok := m.apps[appName]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("app %s not found", appName)
+	}
+	if app.Starting {
+		m.mu.Unlock()
+		return fmt.Errorf("app %s is already starting", appName)
+	}
+	if app.Quarantined {
+		m.mu.Unlock()
+		return fmt.Errorf("app %s is %w", appName, errQuarantined)
+	}
+	app.cancelRestart()
+	app.Starting = true // Keeps other starts out until the new process is up
+	running := app.Running
+	m.mu.Unlock()
+
+	if running {
+		// The process may have exited on its own since, in which case StopApp
+		// fails but there is nothing left to stop
+		err := m.StopApp(appName)
+		m.mu.RLock()
+		if !app.Running {
+			err = nil
+		}
+		m.mu.RUnlock()
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), restartExitTimeout)
+			err = m.waitExited(ctx, appName)
+			cancel()
+		}
+		if err != nil {
+			m.mu.Lock()
+			app.Starting = false
+			m.mu.Unlock()
+			return err
+		}
+	}
+
+	m.mu.RLock()
+	cfg := app.Config
+	m.mu.RUnlock()
+	if err := m.launchStarted(app, cfg); err != nil {
+		return err
+	}
+	m.recordRestart(appName, reason)
+	return nil
+}
+
+// WaitHealthy actively checks an app's health until it reports Healthy or
+// ctx is done
+func (m *Manager) WaitHealthy(ctx context.Context, appName string) error {
+	m.mu.RLock()
+	app, ok := m.apps[appName]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("app %s not found", appName)
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		m.CheckAppHealth(app)
+		m.mu.RLock()
+		status := app.HealthStatus
+		m.mu.RUnlock()
+		if passesHealth(status) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("app %s did not become healthy (last status: %s): %w", appName, status, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// runStep executes a single action step
+func (m *Manager) runStep(step ActionStep) error {
+	d := time.Duration(step.Duration)
+	switch step.Op {
+	case StepStart:
+		return m.StartApp(step.App)
+	case StepStop:
+		return m.StopApp(step.App)
+	case StepRestart:
+		return m.RestartApp(step.App, RestartManual)
+	case StepWaitHealthy:
+		if d == 0 {
+			d = defaultWaitHealthyTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), d)
+		defer cancel()
+		return m.WaitHealthy(ctx, step.App)
+	case StepDelay:
+		time.Sleep(d)
+		return nil
+	default:
+		return fmt.Errorf("unknown step op %q", step.Op)
+	}
+}
+
+// RunAction executes the named action's steps in order, stopping at the
+// first failure. It returns the results of the steps that were run.
+func (m *Manager) RunAction(name string) ([]StepResult, error) {
+	m.mu.RLock()
+	action, ok := m.actions[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("action %s not found", name)
+	}
+
+	log.Printf("Running action: %s", name)
+	results := make([]StepResult, 0, len(action.Steps))
+	for i, step := range action.Steps {
+		start := time.Now()
+		err := m.runStep(step)
+		result := StepResult{Step: step, OK: err == nil, DurationMS: time.Since(start).Milliseconds()}
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			return results, fmt.Errorf("step %d (%s %s) failed: %w", i+1, step.Op, step.App, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// runActionHandler executes an action macro and reports each step
+func runActionHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	results, err := mgr.RunAction(name)
+	if results == nil && err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resp := struct {
+		Action string       `json:"action"`
+		OK     bool         `json:"ok"`
+		Error  string       `json:"error,omitempty"`
+		Steps  []StepResult `json:"steps"`
+	}{Action: name, OK: err == nil, Steps: results}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		resp.Error = err.Error()
+		log.Printf("Action %s failed: %v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding action result for %s: %v", name, err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+)
+
+// Autostart starts every app with Autostart set in StartOrder, once the
+// prerequisites are reachable. With waitReady each tier waits for the one
+// before it to become ready. It gives up if albert shuts down first.
+func (m *Manager) Autostart(waitReady bool) {
+	names := m.selectApps(func(app *AppState) bool { return app.Config.Autostart && !app.Running })
+	if len(names) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-m.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if err := m.waitPrerequisites(ctx); err != nil {
+		log.Printf("Skipping autostart: %v", err)
+		return
+	}
+
+	log.Printf("Autostarting %d apps", len(names))
+	for _, result := range m.StartOrdered(ctx, names, waitReady) {
+		if !result.OK {
+			log.Printf("Failed to autostart %s: %s", result.App, result.Error)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	binarySettleDelay = 2 * time.Second  // Quiet time after the last write before a binary counts as replaced
+	binaryWatchResync = 10 * time.Second // How often the watched directories follow config changes
+)
+
+// fileID identifies one version of a file: a replacement by rename gets a
+// new inode, and an in-place rewrite a new size or modification time
+type fileID struct {
+	dev, ino uint64
+	size     int64
+	modTime  time.Time
+}
+
+// statFileID returns the identity of the file at path
+func statFileID(path string) (fileID, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return fileID{}, err
+	}
+	id := fileID{size: st.Size(), modTime: st.ModTime()}
+	if sys, ok := st.Sys().(*syscall.Stat_t); ok {
+		id.dev, id.ino = uint64(sys.Dev), sys.Ino
+	}
+	return id, nil
+}
+
+// watchedBinary returns the absolute path of the binary to watch for an
+// app with RestartOnBinaryChange, or "" if there is nothing to watch
+func watchedBinary(cfg AppConfig) string {
+	if !cfg.RestartOnBinaryChange || cfg.Shell || cfg.Path == "" {
+		return ""
+	}
+	path := cfg.binaryPath()
+	if !strings.Contains(path, "/") {
+		found, err := exec.LookPath(path)
+		if err != nil {
+			return ""
+		}
+		path = found
+	}
+	if cfg.Chroot != "" {
+		path = filepath.Join(cfg.Chroot, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return abs
+}
+
+// watchBinaries restarts running apps with RestartOnBinaryChange once a new
+// binary has been in place at their Path for binarySettleDelay. Changes are
+// judged by the file's identity against the one the app was started from,
+// so a rename over the binary and an in-place rewrite both count, while a
+// write still in progress or an unchanged file doesn't.
+func (m *Manager) watchBinaries() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Not watching app binaries for changes: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	// Watch directories rather than files, since deploys usually replace
+	// the binary instead of writing it in place
+	watched := map[string]bool{}
+	resync := func() {
+		wanted := map[string]bool{}
+		m.mu.RLock()
+		for _, app := range m.apps {
+			if path := watchedBinary(app.Config); path != "" {
+				wanted[filepath.Dir(path)] = true
+			}
+		}
+		m.mu.RUnlock()
+		for dir := range wanted {
+			if !watched[dir] {
+				if err := watcher.Add(dir); err != nil {
+					log.Printf("Not watching %s for binary changes: %v", dir, err)
+					continue
+				}
+				watched[dir] = true
+			}
+		}
+		for dir := range watched {
+			if !wanted[dir] {
+				watcher.Remove(dir)
+				delete(watched, dir)
+			}
+		}
+	}
+	resync()
+	ticker := time.NewTicker(binaryWatchResync)
+	defer ticker.Stop()
+
+	changed := map[string]bool{}
+	settle := time.NewTimer(binarySettleDelay)
+	settle.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			resync()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Binary watch error: %v", err)
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			changed[filepath.Clean(ev.Name)] = true
+			settle.Reset(binarySettleDelay)
+		case <-settle.C:
+			m.binariesChanged(changed)
+			changed = map[string]bool{}
+		}
+	}
+}
+
+// binariesChanged restarts the running apps whose binary is among paths and
+// is no longer the file they were started from
+func (m *Manager) binariesChanged(paths map[string]bool) {
+	var restart []string
+	m.mu.RLock()
+	for name, app := range m.apps {
+		path := watchedBinary(app.Config)
+		if path == "" || !paths[path] || !app.Running || app.Starting || app.Stopping {
+			continue
+		}
+		id, err := statFileID(path)
+		if err != nil || id.size == 0 {
+			continue // Not (completely) in place yet; its arrival is another event
+		}
+		if id != app.binaryID {
+			restart = append(restart, name)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, name := range restart {
+		appLogf(m.appConfig(name), "Binary of %s changed, restarting it", name)
+		if err := m.RestartApp(name, RestartBinaryChange); err != nil {
+			appLogf(m.appConfig(name), "Failed to restart %s after its binary changed: %v", name, err)
+		}
+	}
+}
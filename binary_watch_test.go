@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenameOverBinaryRestartsOnceAfterSettling(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app")
+	writeScript := func(path, body string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeScript(path, "exec sleep 60")
+
+	m := newTestManager(t, AppConfig{Name: "app", Path: path, RestartOnBinaryChange: true})
+	if err := m.StartApp("app"); err != nil {
+		t.Fatal(err)
+	}
+	go m.watchBinaries()
+	time.Sleep(200 * time.Millisecond) // Let the watcher add the directory
+
+	// Deploy the way most tools do: write alongside, then rename over
+	next := filepath.Join(dir, ".app.new")
+	writeScript(next, "exec sleep 61")
+	replaced := time.Now()
+	if err := os.Rename(next, path); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := func() (runs, restarts int) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		app := m.apps["app"]
+		return app.RunCount, app.Restarts[RestartBinaryChange]
+	}
+	time.Sleep(binarySettleDelay / 2)
+	if runs, _ := counts(); runs != 1 {
+		t.Fatalf("restarted before the settle delay: run count %d", runs)
+	}
+	for deadline := replaced.Add(binarySettleDelay + 3*time.Second); ; {
+		if runs, _ := counts(); runs > 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("not restarted after the binary was replaced")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if waited := time.Since(replaced); waited < binarySettleDelay {
+		t.Errorf("restarted %s after the rename, before the %s settle delay", waited, binarySettleDelay)
+	}
+
+	time.Sleep(binarySettleDelay / 2) // Any second restart would have come by now
+	if runs, restarts := counts(); runs != 2 || restarts != 1 {
+		t.Errorf("run count %d and %d binary_change restarts, want 2 and 1", runs, restarts)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// swapPorts returns the config for running on the other of Port and
+// DeployPort. Must only be called on configs with a DeployPort.
+func (cfg AppConfig) swapPorts() AppConfig {
+	next := *cfg.alternate
+	cfg.alternate = nil
+	next.alternate = &cfg
+	return next
+}
+
+// checkDeployPort checks that an app can be deployed blue/green
+func checkDeployPort(cfg AppConfig) error {
+	switch {
+	case cfg.Port <= 0:
+		return fmt.Errorf("needs port to be set too")
+	case cfg.DeployPort == cfg.Port:
+		return fmt.Errorf("must differ from port %d", cfg.Port)
+	case cfg.Shell:
+		return fmt.Errorf("shell apps have no binary to deploy")
+	}
+	return nil
+}
+
+// swapBinary renames the staged binary over path, keeping the current one
+// at the returned path for a rollback
+func swapBinary(path, staged string) (string, error) {
+	prev := path + ".prev"
+	os.Remove(prev)
+	if err := os.Link(path, prev); err != nil {
+		os.Remove(staged)
+		return "", fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	if err := os.Rename(staged, path); err != nil {
+		os.Remove(staged)
+		return "", fmt.Errorf("failed to swap in new binary: %w", err)
+	}
+	return prev, nil
+}
+
+// waitCandidate waits for a blue/green candidate to pass its readiness
+// probe, or without one, to still be running after deploySettle
+func waitCandidate(ctx context.Context, cfg AppConfig, exited <-chan struct{}) error {
+	if cfg.readyURL() == "" {
+		select {
+		case <-exited:
+			return fmt.Errorf("new instance of %s exited after starting", cfg.Name)
+		case <-time.After(deploySettle):
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("new instance of %s did not settle: %w", cfg.Name, ctx.Err())
+		}
+	}
+
+	probe := cfg.probeFor(cfg.readyURL())
+	ticker := time.NewTicker(durationOr(cfg.ReadyInterval, defaultReadyInterval))
+	defer ticker.Stop()
+	status := "Unknown"
+	for {
+		if result, err := probeHealth(probe); err != nil {
+			status = fmt.Sprintf("Error: %v", err)
+		} else if status = result.Status; passesHealth(status) {
+			return nil
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("new instance of %s exited before becoming ready (last status: %s)", cfg.Name, status)
+		case <-ctx.Done():
+			return fmt.Errorf("new instance of %s did not become ready (last status: %s): %w", cfg.Name, status, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// BlueGreenDeploy swaps in the staged binary and starts it on the app's
+// other port next to the running instance. Once the new instance is ready
+// it becomes the app and the old one is stopped; if it never gets ready it
+// is killed, the previous binary is put back and the old instance keeps
+// serving throughout. The previous binary is removed after the cutover. The
+// caller must hold the app's deployMu.
+func (m *Manager) BlueGreenDeploy(ctx context.Context, appName, staged string) DeployResult {
+	result := DeployResult{App: appName}
+	m.mu.Lock()
+	app, ok := m.apps[appName]
+	if !ok || !app.Running || app.Starting || app.Stopping || app.Config.alternate == nil {
+		m.mu.Unlock()
+		os.Remove(staged)
+		switch {
+		case !ok:
+			result.Error = fmt.Sprintf("app %s not found", appName)
+		case app.Config.alternate == nil:
+			result.Error = fmt.Sprintf("app %s has no deploy_port", appName)
+		case !app.Running:
+			result.Error = fmt.Sprintf("app %s is not running", appName)
+		default:
+			result.Error = fmt.Sprintf("app %s is starting or stopping", appName)
+		}
+		return result
+	}
+	app.Starting = true // Keeps restarts, including the binary watch's, out until the cutover
+	next := app.Config.swapPorts()
+	m.mu.Unlock()
+	done := func() {
+		m.mu.Lock()
+		app.Starting = false
+		m.mu.Unlock()
+	}
+
+	path := next.binaryPath()
+	prev, err := swapBinary(path, staged)
+	if err != nil {
+		done()
+		result.Error = err.Error()
+		return result
+	}
+	log.Printf("Deployed new binary for %s at %s, starting it on port %d", appName, path, next.Port)
+
+	resolved, redact, err := resolveSecrets(next)
+	if err == nil {
+		var cmd *exec.Cmd
+		var output io.Reader
+		if cmd, output, err = launchApp(resolved, m.detachedOutput(resolved)); err == nil {
+			go m.readOutput(app, next, output, redact)
+			exited := m.watchExit(app, cmd)
+			if err = waitCandidate(ctx, resolved, exited); err == nil {
+				m.cutOver(app, next, cmd, exited)
+				m.recordRestart(appName, RestartDeploy)
+				os.Remove(prev)
+				result.OK = true
+				return result
+			}
+			killApp(cmd.Process)
+			<-exited
+		}
+	}
+	done()
+
+	result.Error = err.Error()
+	log.Printf("Blue/green deploy of %s failed, keeping the running instance: %v", appName, err)
+	if rerr := os.Rename(prev, path); rerr != nil {
+		result.Error += fmt.Sprintf("; rollback failed: %v", rerr)
+		return result
+	}
+	result.RolledBack = true
+	return result
+}
+
+// cutOver makes a ready blue/green candidate the app's process and stops the
+// instance it replaces. The old process's exit goes unreported, since it is
+// no longer the app's command.
+func (m *Manager) cutOver(app *AppState, next AppConfig, cmd *exec.Cmd, exited chan struct{}) {
+	m.mu.Lock()
+	prevCfg, prevCmd, prevExited, paused := app.Config, app.Cmd, app.exited, app.Paused
+	app.Config = next
+	app.Cmd = cmd
+	app.exited = exited
+	app.Starting = false
+	app.Running = true
+	app.Paused = false
+	app.Ready = true // Passed its readiness probe as the candidate
+	app.liveFailures = 0
+	app.StartedAt = time.Now()
+	app.outputFile = m.detachedOutput(next)
+	app.healthySince = time.Time{}
+	if path := watchedBinary(next); path != "" {
+		app.binaryID, _ = statFileID(path)
+	}
+	app.LastRequest = app.StartedAt
+	app.RunCount++
+	m.runsChanged()
+	if m.runMarkers {
+		app.writeRunMarker()
+	}
+	app.withdrawMDNS()
+	if next.MDNS {
+		go m.advertiseApp(app, next, cmd)
+	}
+	hub.Publish(EventAppState, app.stateEvent())
+	m.mu.Unlock()
+	appLogf(next, "Switched %s over to the new instance on port %d, stopping the old one on port %d", next.Name, next.Port, prevCfg.Port)
+
+	if prevCmd == nil || prevCmd.Process == nil {
+		return // The old instance exited on its own meanwhile
+	}
+	if err := stopProcess(prevCfg, prevCmd.Process, prevExited, paused); err != nil {
+		appLogf(next, "Failed to stop the old instance of %s: %v", next.Name, err)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultBulkTimeout bounds a bulk operation with no timeout query param
+const defaultBulkTimeout = 30 * time.Second
+
+// BulkResult is the outcome of a bulk operation for one app
+type BulkResult struct {
+	App      string `json:"app"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+}
+
+// isUnhealthy reports whether a health status indicates a failing app
+func isUnhealthy(status string) bool {
+	return strings.HasPrefix(status, "Error") || strings.HasPrefix(status, "Degraded")
+}
+
+// selectApps returns the sorted names of apps matching keep
+func (m *Manager) selectApps(keep func(app *AppState) bool) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var names []string
+	for name, app := range m.apps {
+		if keep(app) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runBulk applies op to each app concurrently and returns once they have
+// all finished or ctx is done, whichever comes first. ctx is passed to each
+// op so it can give up early. Apps whose op hasn't finished by then are
+// reported as timed out; their op carries on in the background (a stop, for
+// one, still waits out StopTimeout) but no longer holds up the results.
+func runBulk(ctx context.Context, names []string, op func(ctx context.Context, name string) error) []BulkResult {
+	type done struct {
+		i   int
+		err error
+	}
+	results := make([]BulkResult, len(names))
+	finished := make(chan done, len(names))
+	for i, name := range names {
+		results[i] = BulkResult{App: name, TimedOut: true, Error: "timed out"}
+		go func(i int, name string) {
+			finished <- done{i, op(ctx, name)}
+		}(i, name)
+	}
+
+	for range names {
+		select {
+		case d := <-finished:
+			results[d.i] = BulkResult{App: names[d.i], OK: d.err == nil}
+			if d.err != nil {
+				results[d.i].Error = d.err.Error()
+				results[d.i].TimedOut = errors.Is(d.err, context.DeadlineExceeded)
+			}
+		case <-ctx.Done():
+			return results
+		}
+	}
+	return results
+}
+
+// bulkHandler runs start-all, stop-all, restart-unhealthy or restart-all
+// across apps, bounded by the optional timeout query param (e.g. ?timeout=10s).
+// Apps not dealt with in time are reported as timed out right away, while a
+// stop or restart already under way finishes in the background. stop-all
+// stops dependents before the apps they depend on. start-all starts apps in
+// StartOrder; with ?wait=true each tier waits for the one before it to
+// become ready. rolling-restart restarts the running apps matching every
+// ?label=key=value one at a time.
+func bulkHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	timeout := defaultBulkTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("Invalid timeout %q", v), http.StatusBadRequest)
+			return
+		}
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	var names []string
+	var op func(context.Context, string) error
+	switch action := r.PathValue("action"); action {
+	case "start-all":
+		if err := mgr.waitPrerequisites(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		names = mgr.selectApps(func(app *AppState) bool { return !app.Running })
+		results := mgr.StartOrdered(ctx, names, r.URL.Query().Get("wait") == "true")
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			log.Printf("Error encoding bulk results: %v", err)
+		}
+		return
+	case "stop-all":
+		names = mgr.selectApps(func(app *AppState) bool { return app.Running })
+		results := mgr.StopOrdered(ctx, names)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			log.Printf("Error encoding bulk results: %v", err)
+		}
+		return
+	case "restart-unhealthy":
+		names = mgr.selectApps(func(app *AppState) bool { return app.Running && isUnhealthy(app.HealthStatus) })
+		op = func(_ context.Context, name string) error { return mgr.RestartApp(name, RestartUnhealthy) }
+	case "rolling-restart":
+		selector, err := parseLabelSelector(r.URL.Query()["label"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		report := mgr.RollingRestart(ctx, selector)
+		w.Header().Set("Content-Type", "application/json")
+		if !report.OK {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Printf("Error encoding rolling restart report: %v", err)
+		}
+		return
+	case "restart-all":
+		report := mgr.RestartAll(ctx, r.URL.Query().Get("ordered") == "true")
+		w.Header().Set("Content-Type", "application/json")
+		if !report.OK {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Printf("Error encoding restart-all report: %v", err)
+		}
+		return
+	default:
+		http.Error(w, "Invalid bulk action. Must be 'start-all', 'stop-all', 'restart-unhealthy', 'restart-all' or 'rolling-restart'.", http.StatusBadRequest)
+		return
+	}
+
+	results := runBulk(ctx, names, op)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Printf("Error encoding bulk results: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunBulkReturnsAtDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	op := func(_ context.Context, name string) error {
+		if name == "stuck" {
+			<-release // Ignores ctx, like a stop waiting out its StopTimeout
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	began := time.Now()
+	results := runBulk(ctx, []string{"quick", "stuck"}, op)
+	if took := time.Since(began); took > time.Second {
+		t.Fatalf("runBulk took %s, want it back at the deadline", took)
+	}
+	if !results[0].OK || results[0].TimedOut {
+		t.Errorf("quick = %+v, want OK", results[0])
+	}
+	if results[1].OK || !results[1].TimedOut {
+		t.Errorf("stuck = %+v, want timed out", results[1])
+	}
+}
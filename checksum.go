@@ -0,0 +1,54 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errChecksumMismatch is wrapped by verifyChecksum when the binary differs
+// from the configured SHA256
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// fileSHA256 returns the hex SHA256 of the file at path
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyChecksum checks the app's binary against its configured SHA256.
+// It hashes the file on every call so a binary swapped since the last start
+// is caught. Absolute paths are resolved inside Chroot, as the app sees them.
+func verifyChecksum(cfg AppConfig) error {
+	if cfg.SHA256 == "" {
+		return nil
+	}
+	if cfg.Shell {
+		return fmt.Errorf("app %s is a shell app and has no binary to check against sha256", cfg.Name)
+	}
+	path := cfg.binaryPath()
+	if cfg.Chroot != "" {
+		path = filepath.Join(cfg.Chroot, path)
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("failed to checksum %s for %s: %w", path, cfg.Name, err)
+	}
+	if !strings.EqualFold(sum, cfg.SHA256) {
+		return fmt.Errorf("app %s binary %s has SHA256 %s, expected %s: %w", cfg.Name, path, sum, cfg.SHA256, errChecksumMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyChecksumInsideChroot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "bin", "app"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := fileSHA256(filepath.Join(root, "bin", "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := AppConfig{Name: "app", Path: "/bin/app", Chroot: root, SHA256: sum}
+	if err := verifyChecksum(cfg); err != nil {
+		t.Errorf("verifyChecksum of the chrooted binary: %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	errAppExists     = errors.New("app already exists")
+	errAppNotFound   = errors.New("app not found")
+	errAppInDropIn   = errors.New("app is defined in a drop-in file")
+	errAppDiscovered = errors.New("discovered apps are read-only; define the app in the config file to override its discovered settings")
+	errConfigTOML    = errors.New("TOML configs can't be edited through the API without losing their comments; edit the file instead")
+)
+
+// saveConfigFile writes fc to path in the format its extension implies,
+// replacing the file atomically
+func saveConfigFile(path string, fc FileConfig) error {
+	data, err := encodeConfig(filepath.Ext(path), fc)
+	if err != nil {
+		return err
+	}
+	return writeConfigData(path, data)
+}
+
+// writeConfigData replaces the config file at path with data atomically,
+// keeping its permissions
+func writeConfigData(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if st, err := os.Stat(path); err == nil {
+		mode = st.Mode().Perm()
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return fmt.Errorf("failed to write config %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace config %s: %w", path, err)
+	}
+	return nil
+}
+
+// encodeConfig encodes fc in the format a file extension implies: YAML for
+// .yaml/.yml, TOML for .toml, anything else JSON. Unset app settings are
+// left out to keep the result readable.
+func encodeConfig(ext string, fc FileConfig) ([]byte, error) {
+	data, err := json.Marshal(fc)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	tree["apps"] = pruneZero(tree["apps"]) // Profile overrides keep their zero values, which are deliberate
+	if data, err = json.MarshalIndent(tree, "", "  "); err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		return yaml.JSONToYAML(data)
+	case ".toml":
+		return jsonToTOML(data)
+	}
+	return append(data, '\n'), nil
+}
+
+// pruneZero drops null, false, zero, empty string and empty collection
+// values from decoded JSON objects
+func pruneZero(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			child = pruneZero(child)
+			if isZeroJSON(child) {
+				delete(v, k)
+			} else {
+				v[k] = child
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = pruneZero(child)
+		}
+	}
+	return v
+}
+
+// isZeroJSON reports whether a decoded JSON value is its type's zero value
+func isZeroJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return v == "" || v == "0s" // Unset Durations encode as "0s"
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// editConfigFile applies edit to the apps in the config file, saves the
+// result and reloads it. Nothing is written unless the edited config is
+// valid. Apps defined in drop-in files are never touched; appName must not
+// be one of them. In a YAML config only appName's entry is rewritten, keeping
+// the rest of the file as it was; JSON configs, which have no comments, are
+// rewritten whole, and TOML configs are refused.
+func (m *Manager) editConfigFile(appName string, restart bool, edit func(apps []AppConfig) ([]AppConfig, error)) (ReloadResult, error) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	if m.configPath == "" {
+		return newReloadResult(), fmt.Errorf("no config file to save changes to; start albert with --config")
+	}
+	if st, err := os.Stat(m.configPath); err == nil && st.IsDir() {
+		return newReloadResult(), fmt.Errorf("config %s is a drop-in directory; edit the app's file instead", m.configPath)
+	}
+	ext := strings.ToLower(filepath.Ext(m.configPath))
+	if ext == ".toml" {
+		return newReloadResult(), fmt.Errorf("config %s: %w", m.configPath, errConfigTOML)
+	}
+	fc, err := loadConfigFile(m.configPath)
+	if err != nil {
+		return newReloadResult(), err
+	}
+	if path, ok := fc.dropInFor(appName); ok {
+		return newReloadResult(), fmt.Errorf("app %s: %w %s; edit that file instead", appName, errAppInDropIn, path)
+	}
+	if fc.Apps, err = edit(fc.Apps); err != nil {
+		if errors.Is(err, errAppNotFound) && slices.ContainsFunc(fc.discovered, func(cfg AppConfig) bool { return cfg.Name == appName }) {
+			err = fmt.Errorf("app %s: %w", appName, errAppDiscovered)
+		}
+		return newReloadResult(), err
+	}
+	if err := validateConfigs(fc.AllApps()); err != nil {
+		return newReloadResult(), err
+	}
+	if _, err := expandConfigs(fc.Apps); err != nil {
+		return newReloadResult(), err
+	}
+	if ext == ".yaml" || ext == ".yml" {
+		err = saveYAMLAppEdit(m.configPath, fc, appName)
+	} else {
+		err = saveConfigFile(m.configPath, fc)
+	}
+	if err != nil {
+		return newReloadResult(), err
+	}
+	return m.reloadLocked(restart, ConfigSourceAPI)
+}
+
+// envDefined reports whether APP_<n>_* environment variables define an app,
+// which would override any change made to it in the config file
+func envDefined(appName string) bool {
+	envConfigs, _ := configsFromEnv(os.Environ())
+	for _, cfg := range envConfigs {
+		if cfg.Name == appName {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeAppConfig reads an AppConfig from a request body, checking it
+// against the config schema
+func decodeAppConfig(r *http.Request) (AppConfig, error) {
+	var tree any
+	if err := json.NewDecoder(r.Body).Decode(&tree); err != nil {
+		return AppConfig{}, fmt.Errorf("invalid app config: %w", err)
+	}
+	if errs := checkSchema(tree, appConfigType, ""); errs != nil {
+		return AppConfig{}, fmt.Errorf("invalid app config: %w", errs)
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return AppConfig{}, err
+	}
+	var cfg AppConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return AppConfig{}, fmt.Errorf("invalid app config: %w", err)
+	}
+	return cfg, nil
+}
+
+// createAppHandler adds a new app to the config file and starts managing it
+func createAppHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	cfg, err := decodeAppConfig(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if envDefined(cfg.Name) {
+		http.Error(w, fmt.Sprintf("App %s is defined by environment variables, which override the config file", cfg.Name), http.StatusConflict)
+		return
+	}
+	result, err := mgr.editConfigFile(cfg.Name, false, func(apps []AppConfig) ([]AppConfig, error) {
+		for _, app := range apps {
+			if app.Name == cfg.Name {
+				return nil, fmt.Errorf("app %s: %w", cfg.Name, errAppExists)
+			}
+		}
+		return append(apps, cfg), nil
+	})
+	writeConfigEditResult(w, result, err, http.StatusCreated)
+}
+
+// updateAppHandler replaces an app's definition in the config file. The
+// app's name comes from the URL and may be left out of the body. With
+// ?restart=true the app is restarted if it's running. Discovered apps are
+// read-only and get 409.
+func updateAppHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	cfg, err := decodeAppConfig(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if cfg.Name == "" {
+		cfg.Name = appName
+	}
+	if cfg.Name != appName {
+		http.Error(w, "App name in the body doesn't match the URL", http.StatusBadRequest)
+		return
+	}
+	if envDefined(appName) {
+		http.Error(w, fmt.Sprintf("App %s is defined by environment variables, which override the config file", appName), http.StatusConflict)
+		return
+	}
+	result, err := mgr.editConfigFile(appName, r.URL.Query().Get("restart") == "true", func(apps []AppConfig) ([]AppConfig, error) {
+		for i, app := range apps {
+			if app.Name == appName {
+				apps[i] = cfg
+				return apps, nil
+			}
+		}
+		return nil, fmt.Errorf("app %s: %w", appName, errAppNotFound)
+	})
+	writeConfigEditResult(w, result, err, http.StatusOK)
+}
+
+// deleteAppHandler removes an app from the config file, stopping it if it's
+// running
+func deleteAppHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if envDefined(appName) {
+		http.Error(w, fmt.Sprintf("App %s is defined by environment variables, which override the config file", appName), http.StatusConflict)
+		return
+	}
+	result, err := mgr.editConfigFile(appName, false, func(apps []AppConfig) ([]AppConfig, error) {
+		for i, app := range apps {
+			if app.Name == appName {
+				return append(apps[:i], apps[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("app %s: %w", appName, errAppNotFound)
+	})
+	writeConfigEditResult(w, result, err, http.StatusOK)
+}
+
+// writeConfigEditResult reports the reload that followed a config edit
+func writeConfigEditResult(w http.ResponseWriter, result ReloadResult, err error, okStatus int) {
+	status := okStatus
+	switch {
+	case errors.Is(err, errAppExists), errors.Is(err, errAppInDropIn), errors.Is(err, errAppDiscovered), errors.Is(err, errConfigTOML):
+		status = http.StatusConflict
+	case errors.Is(err, errAppNotFound):
+		status = http.StatusNotFound
+	case err != nil:
+		status = http.StatusBadRequest
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding config edit result: %v", err)
+	}
+}
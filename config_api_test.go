@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateDiscoveredAppIsConflict(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "tools", "heckler"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tools", "heckler", "heckler"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "albert.yaml")
+	if err := os.WriteFile(path, []byte("discover:\n  - dir: tools\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fc, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newTestManager(t, fc.AllApps()...)
+	m.configPath = path
+
+	put := func(name string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PUT", "/api/app/"+name, strings.NewReader(`{"path": "sleep", "args": ["60"]}`))
+		r.SetPathValue("name", name)
+		w := httptest.NewRecorder()
+		updateAppHandler(m, w, r)
+		return w
+	}
+	if w := put("heckler"); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "read-only") {
+		t.Errorf("PUT discovered app: %d %s, want 409 saying it's read-only", w.Code, w.Body)
+	}
+	if w := put("nobody"); w.Code != http.StatusNotFound {
+		t.Errorf("PUT unknown app: %d %s, want 404", w.Code, w.Body)
+	}
+}
+
+func TestUpdateAppKeepsYAMLComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "albert.yaml")
+	orig := `# Apps for the stream
+apps:
+  # The chat bot
+  - name: bot
+    path: sleep
+    args: ["60"] # Long enough for a test
+  # Overlay server
+  - name: overlay
+    path: sleep
+    args: ["60"]
+`
+	if err := os.WriteFile(path, []byte(orig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fc, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newTestManager(t, fc.AllApps()...)
+	m.configPath = path
+
+	r := httptest.NewRequest("PUT", "/api/app/overlay", strings.NewReader(`{"path": "sleep", "args": ["120"]}`))
+	r.SetPathValue("name", "overlay")
+	w := httptest.NewRecorder()
+	updateAppHandler(m, w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT overlay: %d %s", w.Code, w.Body)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{"# Apps for the stream", "# The chat bot", "# Long enough for a test", "# Overlay server", "120"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("saved config lost %q:\n%s", want, got)
+		}
+	}
+	if fc, err = loadConfigFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Apps) != 2 || fc.Apps[0].Args[0] != "60" || fc.Apps[1].Args[0] != "120" {
+		t.Errorf("saved apps = %+v, want bot unchanged and overlay updated", fc.Apps)
+	}
+}
+
+func TestUpdateAppRefusesTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "albert.toml")
+	orig := "# The chat bot\n[[apps]]\nname = \"bot\"\npath = \"sleep\"\n"
+	if err := os.WriteFile(path, []byte(orig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestManager(t, AppConfig{Name: "bot", Path: "sleep"})
+	m.configPath = path
+
+	r := httptest.NewRequest("PUT", "/api/app/bot", strings.NewReader(`{"path": "true"}`))
+	r.SetPathValue("name", "bot")
+	w := httptest.NewRecorder()
+	updateAppHandler(m, w, r)
+	if w.Code != http.StatusConflict {
+		t.Errorf("PUT into TOML config: %d %s, want 409", w.Code, w.Body)
+	}
+	if data, _ := os.ReadFile(path); string(data) != orig {
+		t.Errorf("TOML config was rewritten:\n%s", data)
+	}
+}
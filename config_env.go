@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var appEnvKey = regexp.MustCompile(`^APP_(\d+)_([A-Z_]+)$`)
+
+// configsFromEnv assembles app definitions from numbered environment
+// variables such as APP_1_NAME, APP_1_PATH, APP_1_ARGS, APP_1_HEALTH_URL,
+// APP_1_PORT, APP_1_PRIORITY, APP_1_SHELL, APP_1_WORKDIR, APP_1_RESTART and APP_1_AUTOSTART. ARGS is split on whitespace, or parsed as a
+// JSON array when it starts with '['. Other APP_<n>_* variables are logged
+// and ignored. Apps are returned in index order.
+func configsFromEnv(environ []string) ([]AppConfig, error) {
+	byIndex := map[int]*AppConfig{}
+	for _, kv := range environ {
+		k, v, _ := strings.Cut(kv, "=")
+		match := appEnvKey.FindStringSubmatch(k)
+		if match == nil {
+			continue
+		}
+		idx, _ := strconv.Atoi(match[1])
+		cfg, ok := byIndex[idx]
+		if !ok {
+			cfg = &AppConfig{} // Kept once it gets a known setting
+		}
+
+		var err error
+		switch match[2] {
+		case "NAME":
+			cfg.Name = v
+		case "PATH":
+			cfg.Path = v
+		case "ARGS":
+			if strings.HasPrefix(strings.TrimSpace(v), "[") {
+				err = json.Unmarshal([]byte(v), &cfg.Args)
+			} else {
+				cfg.Args = strings.Fields(v)
+			}
+		case "HEALTH_URL":
+			cfg.HealthURL = v
+		case "PORT":
+			cfg.Port, err = strconv.Atoi(v)
+		case "PRIORITY":
+			cfg.Priority, err = strconv.Atoi(v)
+		case "SHELL":
+			cfg.Shell, err = strconv.ParseBool(v)
+		case "WORKDIR":
+			cfg.WorkDir = v
+		case "RESTART":
+			cfg.Restart = v
+		case "AUTOSTART":
+			cfg.Autostart, err = strconv.ParseBool(v)
+		default:
+			log.Printf("Ignoring %s: unknown app setting", k) // Likely meant for something else
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", k, err)
+		}
+		byIndex[idx] = cfg
+	}
+
+	indexes := make([]int, 0, len(byIndex))
+	for idx := range byIndex {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	configs := make([]AppConfig, 0, len(indexes))
+	seen := map[string]int{}
+	for _, idx := range indexes {
+		cfg := byIndex[idx]
+		if cfg.Name == "" || cfg.Path == "" {
+			return nil, fmt.Errorf("APP_%d needs both APP_%d_NAME and APP_%d_PATH", idx, idx, idx)
+		}
+		if prev, dup := seen[cfg.Name]; dup {
+			return nil, fmt.Errorf("APP_%d and APP_%d are both named %q", prev, idx, cfg.Name)
+		}
+		seen[cfg.Name] = idx
+		configs = append(configs, *cfg)
+	}
+	return configs, nil
+}
+
+// mergeConfigs overlays app definitions onto base: entries with a matching
+// name replace the base entry in place, others are appended
+func mergeConfigs(base, overlay []AppConfig) []AppConfig {
+	merged := append([]AppConfig(nil), base...)
+	for _, cfg := range overlay {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == cfg.Name {
+				merged[i] = cfg
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, cfg)
+		}
+	}
+	return merged
+}
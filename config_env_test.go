@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestConfigsFromEnvIgnoresUnknownSettings(t *testing.T) {
+	configs, err := configsFromEnv([]string{
+		"APP_1_NAME=web",
+		"APP_1_PATH=/usr/bin/web",
+		"APP_1_COLOUR=blue",
+		"APP_2_UNRELATED=1",
+	})
+	if err != nil {
+		t.Fatalf("configsFromEnv: %v", err)
+	}
+	if len(configs) != 1 || configs[0].Name != "web" || configs[0].Path != "/usr/bin/web" {
+		t.Errorf("configs = %+v, want just web", configs)
+	}
+}
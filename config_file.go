@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// FileConfig is the layout of the --config file
+type FileConfig struct {
+	Apps []AppConfig `json:"apps"`
+
+	// Drop-in directory holding one app definition per file, merged after
+	// Apps. Relative paths are relative to the config file.
+	IncludeDir string `json:"include_dir,omitempty"`
+
+	// Named variations of the app list, chosen with --profile or
+	// PUT /api/profile
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// Base directories scanned for apps on every load. Apps defined in the
+	// file or its drop-ins take precedence over discovered ones.
+	Discover []Discovery `json:"discover,omitempty"`
+
+	dropIns    []dropIn    // Apps read from IncludeDir
+	includeDir string      // IncludeDir resolved against the config file's directory
+	discovered []AppConfig // Apps found by Discover
+}
+
+// dropIn is an app defined in its own file
+type dropIn struct {
+	Path   string
+	Config AppConfig
+}
+
+// AllApps returns the apps defined in the file followed by its drop-ins and
+// then any discovered apps not already defined
+func (fc FileConfig) AllApps() []AppConfig {
+	apps := append([]AppConfig(nil), fc.Apps...)
+	for _, d := range fc.dropIns {
+		apps = append(apps, d.Config)
+	}
+	defined := make(map[string]bool, len(apps))
+	for _, cfg := range apps {
+		defined[cfg.Name] = true
+	}
+	for _, cfg := range fc.discovered {
+		if !defined[cfg.Name] {
+			apps = append(apps, cfg)
+		}
+	}
+	return apps
+}
+
+// dropInFor returns the drop-in file that defines an app, if any
+func (fc FileConfig) dropInFor(appName string) (string, bool) {
+	for _, d := range fc.dropIns {
+		if d.Config.Name == appName {
+			return d.Path, true
+		}
+	}
+	return "", false
+}
+
+// loadConfigFile reads and validates app definitions from a config file
+func loadConfigFile(path string) (FileConfig, error) {
+	fc, err := parseConfigFile(path)
+	if err != nil {
+		return FileConfig{}, err
+	}
+	if err := validateConfigs(fc.AllApps()); err != nil {
+		return FileConfig{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return fc, nil
+}
+
+// parseConfigFile reads app definitions from a config file and its drop-in
+// directory, and runs its discoveries. If path is itself a directory it is
+// read as a drop-in directory with no main file.
+func parseConfigFile(path string) (FileConfig, error) {
+	var fc FileConfig
+	st, err := os.Stat(path)
+	if err != nil {
+		return FileConfig{}, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	if st.IsDir() {
+		fc.IncludeDir = path
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return FileConfig{}, fmt.Errorf("failed to read config %s: %w", path, err)
+		}
+		if err := decodeConfig(path, data, &fc); err != nil {
+			return FileConfig{}, err
+		}
+	}
+
+	if fc.discovered, err = discoverApps(fc.Discover, filepath.Dir(path)); err != nil {
+		return FileConfig{}, err
+	}
+	if fc.IncludeDir == "" {
+		return fc, nil
+	}
+	dir := fc.IncludeDir
+	if !filepath.IsAbs(dir) && !st.IsDir() {
+		dir = filepath.Join(filepath.Dir(path), dir)
+	}
+	fc.includeDir = dir
+	if fc.dropIns, err = readDropIns(dir); err != nil {
+		return FileConfig{}, err
+	}
+	return fc, nil
+}
+
+// readDropIns reads every config file in dir, in name order, as one app each.
+// Hidden files and files without a config extension are skipped.
+func readDropIns(dir string) ([]dropIn, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read drop-in directory %s: %w", dir, err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || !isConfigExt(e.Name()) {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	dropIns := make([]dropIn, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+		}
+		var cfg AppConfig
+		if err := decodeConfig(path, data, &cfg); err != nil {
+			return nil, err
+		}
+		dropIns = append(dropIns, dropIn{Path: path, Config: cfg})
+	}
+	return dropIns, nil
+}
+
+// isConfigExt reports whether a file name has a config file extension
+func isConfigExt(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml", ".toml":
+		return true
+	}
+	return false
+}
+
+// decodeConfig decodes JSON, YAML or TOML config data into v, chosen by the
+// file's extension (.yaml/.yml for YAML, .toml for TOML, anything else
+// JSON). YAML and TOML use the same field names as JSON.
+func decodeConfig(path string, data []byte, v any) error {
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.YAMLToJSON(data)
+	case ".toml":
+		data, err = tomlToJSON(data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	// Check against the schema first for errors that say where the problem is
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if errs := checkSchema(tree, reflect.TypeOf(v).Elem(), ""); errs != nil {
+		return fmt.Errorf("invalid config %s: %w", path, errs)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields() // Catch misspelled settings instead of silently ignoring them
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// validateConfigs checks that every app has a name and path, and that names
+// are unique
+func validateConfigs(configs []AppConfig) error {
+	seen := map[string]bool{}
+	for i, cfg := range configs {
+		if cfg.Name == "" {
+			return fmt.Errorf("app #%d has no name", i+1)
+		}
+		if cfg.Path == "" {
+			return fmt.Errorf("app %s has no path", cfg.Name)
+		}
+		if seen[cfg.Name] {
+			return fmt.Errorf("app %s is defined more than once", cfg.Name)
+		}
+		seen[cfg.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Where a config version came from
+const (
+	ConfigSourceStartup  = "startup"
+	ConfigSourceReload   = "reload"   // SIGHUP, POST /api/reload or an applied file change
+	ConfigSourceAPI      = "api"      // App created, updated or deleted through the API
+	ConfigSourceProfile  = "profile"  // Profile switch
+	ConfigSourceRollback = "rollback" // Rollback to an earlier version
+)
+
+// configHistoryLimit is how many config versions are kept
+const configHistoryLimit = 20
+
+var errVersionNotFound = errors.New("config version not found")
+
+// ConfigVersion is a snapshot of the effective config: every app as albert
+// runs it, after profiles, environment overrides and templates
+type ConfigVersion struct {
+	Version    int         `json:"version"`
+	Time       time.Time   `json:"time"`
+	Source     string      `json:"source"`
+	Profile    string      `json:"profile,omitempty"`
+	RolledBack int         `json:"rolled_back_to,omitempty"` // Version restored, for rollbacks
+	Apps       []AppConfig `json:"apps"`
+
+	file []byte // The config file as it was, restored on rollback; nil without one
+}
+
+// recordConfigVersion adds configs to the history, dropping the oldest
+// version past configHistoryLimit. Must be called with m.reloadMu held.
+func (m *Manager) recordConfigVersion(source string, configs []AppConfig) *ConfigVersion {
+	version := ConfigVersion{
+		Version: len(m.history) + 1,
+		Time:    time.Now(),
+		Source:  source,
+		Profile: m.profile,
+		Apps:    configs,
+	}
+	if n := len(m.history); n > 0 {
+		version.Version = m.history[n-1].Version + 1
+	}
+	if m.configPath != "" {
+		if st, err := os.Stat(m.configPath); err == nil && st.Mode().IsRegular() {
+			version.file, _ = os.ReadFile(m.configPath)
+		}
+	}
+	m.history = append(m.history, version)
+	if len(m.history) > configHistoryLimit {
+		m.history = append([]ConfigVersion(nil), m.history[len(m.history)-configHistoryLimit:]...)
+	}
+	return &m.history[len(m.history)-1]
+}
+
+// RecordStartupConfig records the config albert started with as the first
+// version
+func (m *Manager) RecordStartupConfig(configs []AppConfig) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.recordConfigVersion(ConfigSourceStartup, configs)
+}
+
+// ConfigHistory returns the recorded config versions, newest first
+func (m *Manager) ConfigHistory() []ConfigVersion {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	history := make([]ConfigVersion, len(m.history))
+	for i, v := range m.history {
+		history[len(m.history)-1-i] = v
+	}
+	return history
+}
+
+// Rollback makes an earlier config version the running config again, and
+// restores the config file as it was then so later reloads keep it. Drop-in
+// files aren't restored. The rollback itself is recorded as a new version.
+func (m *Manager) Rollback(version int, restartChanged bool) (ReloadResult, error) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	var target *ConfigVersion
+	for i := range m.history {
+		if m.history[i].Version == version {
+			target = &m.history[i]
+		}
+	}
+	if target == nil {
+		return newReloadResult(), fmt.Errorf("version %d: %w", version, errVersionNotFound)
+	}
+	if target.file != nil {
+		if err := writeConfigData(m.configPath, target.file); err != nil {
+			return newReloadResult(), err
+		}
+	}
+
+	m.profile = target.Profile
+	configs := target.Apps
+	result := m.applyConfigsLocked(configs, restartChanged)
+	m.recordConfigVersion(ConfigSourceRollback, configs).RolledBack = version
+	log.Printf("Rolled back config to version %d: %d added, %d removed, %d changed, %d restarted",
+		version, len(result.Added), len(result.Removed), len(result.Changed), len(result.Restarted))
+	return result, nil
+}
+
+// configHistoryHandler lists the recorded config versions, newest first
+func configHistoryHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(mgr.ConfigHistory()); err != nil {
+		log.Printf("Error encoding config history: %v", err)
+	}
+}
+
+// rollbackHandler rolls back to the version in the URL. With ?restart=true
+// running apps whose config changed are restarted.
+func rollbackHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	result, err := mgr.Rollback(version, r.URL.Query().Get("restart") == "true")
+	status := http.StatusOK
+	switch {
+	case errors.Is(err, errVersionNotFound):
+		status = http.StatusNotFound
+	case err != nil:
+		status = http.StatusInternalServerError
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding rollback result: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tomlToJSON converts a TOML document to JSON so it decodes with the same
+// field names and types as the JSON and YAML formats
+func tomlToJSON(data []byte) ([]byte, error) {
+	var tree map[string]any
+	if _, err := toml.NewDecoder(bytes.NewReader(data)).Decode(&tree); err != nil {
+		return nil, err
+	}
+	return json.Marshal(tree)
+}
+
+// jsonToTOML converts a JSON document to TOML
+func jsonToTOML(data []byte) ([]byte, error) {
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(tomlValue(tree)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// tomlValue prepares decoded JSON for the TOML encoder: whole numbers become
+// integers, since JSON decodes every number as a float, and nulls, which TOML
+// can't express, are dropped
+func tomlValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if child == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = tomlValue(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = tomlValue(child)
+		}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	}
+	return v
+}
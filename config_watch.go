@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configSettleDelay lets editors finish writing before the config is read
+const configSettleDelay = 500 * time.Millisecond
+
+// ConfigDiff describes what reloading the config file would change
+type ConfigDiff struct {
+	Added   []string                 `json:"added"`
+	Removed []string                 `json:"removed"`
+	Changed map[string][]FieldChange `json:"changed"`
+	Error   string                   `json:"error,omitempty"`
+}
+
+// FieldChange is one setting that differs between the running and new config
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// empty reports whether the diff has no changes
+func (d ConfigDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// ConfigDiff compares the config file with the running config without
+// applying anything
+func (m *Manager) ConfigDiff() (ConfigDiff, error) {
+	diff := ConfigDiff{Added: []string{}, Removed: []string{}, Changed: map[string][]FieldChange{}}
+	m.reloadMu.Lock()
+	configs, err := m.loadConfigs()
+	m.reloadMu.Unlock()
+	if err != nil {
+		return diff, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	wanted := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		wanted[cfg.Name] = true
+		app, ok := m.apps[cfg.Name]
+		if !ok {
+			diff.Added = append(diff.Added, cfg.Name)
+			continue
+		}
+		if changes := diffAppConfigs(app.Config, cfg); len(changes) > 0 {
+			diff.Changed[cfg.Name] = changes
+		}
+	}
+	for name := range m.apps {
+		if !wanted[name] {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Removed)
+	return diff, nil
+}
+
+// diffAppConfigs lists the settings that differ between two configs, by
+// their config file names
+func diffAppConfigs(old, new AppConfig) []FieldChange {
+	var changes []FieldChange
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue // Derived state like alternate, not a setting
+		}
+		a, b := ov.Field(i).Interface(), nv.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		changes = append(changes, FieldChange{Field: name, Old: a, New: b})
+	}
+	return changes
+}
+
+// watchConfig watches the config file and its drop-in directory. When they
+// change, the difference from the running config is logged and published as
+// a config_changed event, or applied right away if autoApply is set. The
+// watched drop-in directory is the one configured at startup.
+func (m *Manager) watchConfig(autoApply bool) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Not watching config for changes: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	// Watch directories rather than files, since editors and saveConfigFile
+	// replace the file instead of writing it in place
+	configFile := filepath.Clean(m.configPath)
+	var includeDir string
+	if fc, err := parseConfigFile(configFile); err == nil && fc.includeDir != "" {
+		includeDir = filepath.Clean(fc.includeDir)
+	}
+	dirs := []string{filepath.Dir(configFile)}
+	if includeDir == configFile {
+		dirs = nil // --config names the drop-in directory itself
+	}
+	if includeDir != "" {
+		dirs = append(dirs, includeDir)
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			log.Printf("Not watching %s for config changes: %v", dir, err)
+		}
+	}
+
+	settle := time.NewTimer(configSettleDelay)
+	settle.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Config watch error: %v", err)
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			path := filepath.Clean(ev.Name)
+			dropIn := includeDir != "" && filepath.Dir(path) == includeDir && isConfigExt(path)
+			if ev.Op == fsnotify.Chmod || (path != configFile && !dropIn) {
+				continue
+			}
+			settle.Reset(configSettleDelay)
+		case <-settle.C:
+			m.configChanged(autoApply)
+		}
+	}
+}
+
+// configChanged handles a change to the config on disk
+func (m *Manager) configChanged(autoApply bool) {
+	diff, err := m.ConfigDiff()
+	if err != nil {
+		diff.Error = err.Error()
+		log.Printf("Config file changed but can't be loaded: %v", err)
+		hub.Publish(EventConfigChanged, diff)
+		return
+	}
+	if diff.empty() {
+		return
+	}
+	if autoApply {
+		log.Printf("Config file changed, applying")
+		m.Reload(false)
+		return
+	}
+	log.Printf("Config file changed: %d added, %d removed, %d changed; see GET /api/config/diff and apply with POST /api/reload",
+		len(diff.Added), len(diff.Removed), len(diff.Changed))
+	hub.Publish(EventConfigChanged, diff)
+}
+
+// configDiffHandler shows what reloading the config file would change
+func configDiffHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	diff, err := mgr.ConfigDiff()
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		diff.Error = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if err := json.NewEncoder(w).Encode(diff); err != nil {
+		log.Printf("Error encoding config diff: %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestDiffAppConfigsSkipsUnexportedFields(t *testing.T) {
+	old := AppConfig{Name: "web", Port: 8080, DeployPort: 8081}
+	old.alternate = &AppConfig{Name: "web", Port: 8081, DeployPort: 8080}
+	new := old
+	new.Args = []string{"--verbose"}
+	new.alternate = &AppConfig{Name: "web", Port: 8081, DeployPort: 8080, Args: []string{"--verbose"}}
+
+	changes := diffAppConfigs(old, new)
+	if len(changes) != 1 || changes[0].Field != "args" {
+		t.Errorf("changes = %+v, want only args", changes)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// saveYAMLAppEdit writes an API edit of one app to the YAML config at path.
+// Only that app's entry under apps is replaced, added or removed, so the
+// comments and layout of the rest of the file survive.
+func saveYAMLAppEdit(path string, fc FileConfig, appName string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if doc.Kind == 0 { // Empty file
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s: top level isn't a mapping", path)
+	}
+	apps := yamlMapValue(root, "apps")
+	if apps == nil {
+		apps = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "apps"}, apps)
+	}
+	if apps.Kind != yaml.SequenceNode {
+		return fmt.Errorf("config %s: apps isn't a list", path)
+	}
+
+	i := slices.IndexFunc(apps.Content, func(n *yaml.Node) bool {
+		name := yamlMapValue(n, "name")
+		return name != nil && name.Value == appName
+	})
+	j := slices.IndexFunc(fc.Apps, func(cfg AppConfig) bool { return cfg.Name == appName })
+	switch {
+	case j < 0 && i >= 0:
+		apps.Content = slices.Delete(apps.Content, i, i+1)
+	case j >= 0:
+		node, err := yamlAppNode(fc.Apps[j])
+		if err != nil {
+			return err
+		}
+		if i < 0 {
+			apps.Content = append(apps.Content, node)
+			break
+		}
+		old := apps.Content[i]
+		node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		apps.Content[i] = node
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return writeConfigData(path, buf.Bytes())
+}
+
+// yamlMapValue returns the value of key in a YAML mapping node, or nil if
+// n isn't a mapping or doesn't have key
+func yamlMapValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlAppNode encodes cfg as a YAML node the way encodeConfig writes apps,
+// leaving out unset settings, but with settings in AppConfig's field order
+func yamlAppNode(cfg AppConfig) (*yaml.Node, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil { // JSON is YAML
+		return nil, err
+	}
+	n := doc.Content[0]
+	pruneZeroYAML(n)
+	return n, nil
+}
+
+// pruneZeroYAML is pruneZero for YAML nodes. It also switches them from
+// the JSON flow style they were decoded with to block style, quoting only
+// strings that decodeConfig would otherwise read as something else.
+func pruneZeroYAML(n *yaml.Node) {
+	if n.Kind != yaml.ScalarNode || n.ShortTag() != "!!str" || plainYAMLString(n.Value) {
+		n.Style = 0
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		var kept []*yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			pruneZeroYAML(key)
+			pruneZeroYAML(value)
+			if !isZeroYAML(value) {
+				kept = append(kept, key, value)
+			}
+		}
+		n.Content = kept
+	case yaml.SequenceNode:
+		for _, child := range n.Content {
+			pruneZeroYAML(child)
+		}
+	}
+}
+
+// plainYAMLString reports whether s still reads as the string s when left
+// unquoted. decodeConfig's YAML 1.1 rules take e.g. y and on to be booleans.
+func plainYAMLString(s string) bool {
+	var v any
+	return sigsyaml.Unmarshal([]byte(s), &v) == nil && v == s
+}
+
+// isZeroYAML is isZeroJSON for YAML nodes
+func isZeroYAML(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(n.Content) == 0
+	}
+	switch n.ShortTag() {
+	case "!!null":
+		return true
+	case "!!bool":
+		return n.Value == "false"
+	case "!!int", "!!float":
+		return n.Value == "0"
+	case "!!str":
+		return n.Value == "" || n.Value == "0s" // Unset Durations encode as "0s"
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package main
+
+import "time"
+
+// Crash alert severities. An alert escalates to critical when crashes had to
+// be suppressed since the previous one, i.e. the app kept crashing for at
+// least a whole CrashAlertInterval.
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// crashAlert decides whether a crash at now should be announced, returning
+// the event to send. Crashes within CrashAlertInterval of the last alert are
+// only counted. Must be called with m.mu held.
+func (app *AppState) crashAlert(err error, now time.Time) (CrashEvent, bool) {
+	interval := time.Duration(app.Config.CrashAlertInterval)
+	if interval > 0 && !app.lastCrashAlert.IsZero() && now.Sub(app.lastCrashAlert) < interval {
+		app.suppressedCrashes++
+		return CrashEvent{}, false
+	}
+
+	event := CrashEvent{
+		Type:       "app_crashed",
+		App:        app.Config.Name,
+		Error:      err.Error(),
+		Severity:   SeverityWarning,
+		Suppressed: app.suppressedCrashes,
+		Time:       now,
+	}
+	if app.suppressedCrashes > 0 {
+		event.Severity = SeverityCritical
+	}
+	app.lastCrashAlert = now
+	app.suppressedCrashes = 0
+	return event, true
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Defaults for crash-loop detection
+const (
+	defaultCrashLoopRestarts = 5
+	defaultCrashLoopWindow   = 5 * time.Minute
+)
+
+// healthCrashLooping is the HealthStatus of a quarantined app
+const healthCrashLooping = "CrashLooping"
+
+var errQuarantined = errors.New("quarantined after crash-looping; reset it first")
+
+// QuarantineEvent is sent over SSE once when an app is quarantined
+type QuarantineEvent struct {
+	Type     string    `json:"type"`
+	App      string    `json:"app"`
+	Restarts int       `json:"restarts"` // Crash restarts within the window
+	Window   Duration  `json:"window"`
+	Error    string    `json:"error"` // The crash that tipped it over
+	Time     time.Time `json:"time"`
+}
+
+// crashLooping counts a crash restart about to be scheduled at now and
+// reports whether it would be more than CrashLoopRestarts within
+// CrashLoopWindow, in which case it isn't counted. Must be called with m.mu
+// held.
+func (app *AppState) crashLooping(now time.Time) bool {
+	limit := app.Config.CrashLoopRestarts
+	if limit < 0 {
+		return false // Detection disabled
+	}
+	if limit == 0 {
+		limit = defaultCrashLoopRestarts
+	}
+	cutoff := now.Add(-durationOr(app.Config.CrashLoopWindow, defaultCrashLoopWindow))
+	recent := app.crashRestarts[:0]
+	for _, t := range app.crashRestarts {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	app.crashRestarts = recent
+	if len(recent) >= limit {
+		return true
+	}
+	app.crashRestarts = append(app.crashRestarts, now)
+	return false
+}
+
+// quarantine stops retrying a crash-looping app and alerts about it once.
+// It stays quarantined until ResetApp. Must be called with m.mu held.
+func (app *AppState) quarantine(exitErr error, now time.Time) {
+	app.cancelRestart()
+	app.Quarantined = true
+	app.HealthStatus = healthCrashLooping
+	window := Duration(durationOr(app.Config.CrashLoopWindow, defaultCrashLoopWindow))
+	appLogf(app.Config, "App %s crashed again after %d restarts within %s, quarantining it until it is reset", app.Config.Name, len(app.crashRestarts), time.Duration(window))
+
+	event := QuarantineEvent{
+		Type:     "app_quarantined",
+		App:      app.Config.Name,
+		Restarts: len(app.crashRestarts),
+		Window:   window,
+		Error:    exitErr.Error(),
+		Time:     now,
+	}
+	go emitCriticalEvent(event)
+	hub.Publish(EventQuarantine, event)
+}
+
+// ResetApp lifts an app's quarantine and forgets its crash history, so its
+// restart policy applies afresh. It does not start the app.
+func (m *Manager) ResetApp(appName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	app, ok := m.apps[appName]
+	if !ok {
+		return fmt.Errorf("app %s not found", appName)
+	}
+	wasQuarantined := app.Quarantined
+	app.cancelRestart()
+	app.Quarantined = false
+	app.BackoffAttempt = 0
+	app.crashRestarts = nil
+	if app.HealthStatus == healthCrashLooping {
+		app.HealthStatus = "Stopped"
+	}
+	hub.Publish(EventAppState, app.stateEvent())
+	if wasQuarantined {
+		appLogf(app.Config, "Lifted the quarantine of %s", appName)
+	}
+	return nil
+}
+
+// resetAppHandler lifts an app's quarantine. With ?start=true the app is
+// started right after.
+func resetAppHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	if err := mgr.ResetApp(appName); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if r.URL.Query().Get("start") == "true" {
+		if err := mgr.StartApp(appName); err != nil {
+			http.Error(w, fmt.Sprintf("Reset app %s but failed to start it: %v", appName, err), http.StatusInternalServerError)
+			log.Printf("Error starting app %s after reset: %v", appName, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "{\"status\": \"success\", \"message\": \"reset app %s\"}", appName)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// Capabilities needed to switch user and group, from linux/capability.h
+const (
+	capSetgid = 6
+	capSetuid = 7
+)
+
+// appCredential resolves an app's User and Group, as names or numeric ids,
+// to the credential its process runs with. Group defaults to the user's
+// primary group, and a named user also gets its supplementary groups.
+// Returns nil when the app runs as albert's own user and group.
+func appCredential(cfg AppConfig) (*syscall.Credential, error) {
+	if cfg.User == "" && cfg.Group == "" {
+		return nil, nil
+	}
+
+	cred := &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid()), NoSetGroups: true}
+	if cfg.User != "" {
+		u, err := lookupUser(cfg.User)
+		if err != nil {
+			return nil, fmt.Errorf("app %s: %w", cfg.Name, err)
+		}
+		uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+		gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+		cred.Uid, cred.Gid = uint32(uid), uint32(gid)
+		if groupIDs, err := u.GroupIds(); err == nil {
+			cred.NoSetGroups = false
+			for _, id := range groupIDs {
+				if gid, err := strconv.ParseUint(id, 10, 32); err == nil {
+					cred.Groups = append(cred.Groups, uint32(gid))
+				}
+			}
+		}
+	}
+	if cfg.Group != "" {
+		g, err := lookupGroup(cfg.Group)
+		if err != nil {
+			return nil, fmt.Errorf("app %s: %w", cfg.Name, err)
+		}
+		gid, _ := strconv.ParseUint(g.Gid, 10, 32)
+		cred.Gid = uint32(gid)
+	}
+
+	if cred.Uid == uint32(os.Getuid()) && cred.Gid == uint32(os.Getgid()) && cred.NoSetGroups {
+		return nil, nil
+	}
+	if cred.Uid != uint32(os.Getuid()) {
+		if ok, err := hasCapability(capSetuid); err != nil {
+			return nil, fmt.Errorf("failed to check privileges for %s: %w", cfg.Name, err)
+		} else if !ok {
+			return nil, fmt.Errorf("app %s needs CAP_SETUID (run albert as root) to run as user %s", cfg.Name, cfg.User)
+		}
+	}
+	if cred.Gid != uint32(os.Getgid()) || !cred.NoSetGroups {
+		if ok, err := hasCapability(capSetgid); err != nil {
+			return nil, fmt.Errorf("failed to check privileges for %s: %w", cfg.Name, err)
+		} else if !ok {
+			return nil, fmt.Errorf("app %s needs CAP_SETGID (run albert as root) to change its group", cfg.Name)
+		}
+	}
+	return cred, nil
+}
+
+// lookupUser finds a user by name, or by id if s is numeric
+func lookupUser(s string) (*user.User, error) {
+	if _, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return user.LookupId(s)
+	}
+	return user.Lookup(s)
+}
+
+// lookupGroup finds a group by name, or by id if s is numeric
+func lookupGroup(s string) (*user.Group, error) {
+	if _, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return user.LookupGroupId(s)
+	}
+	return user.LookupGroup(s)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// startWithDependencies starts an app once every app it depends on is
+// running and ready, starting those first as needed. chain lists the apps
+// whose start led here, to catch dependency cycles.
+func (m *Manager) startWithDependencies(appName string, chain []string) error {
+	app, cfg, err := m.beginStart(appName)
+	if err != nil {
+		return err
+	}
+	chain = slices.Concat(chain, []string{appName})
+	for _, dep := range cfg.DependsOn {
+		if err = m.ensureReady(dep, chain); err != nil {
+			err = fmt.Errorf("dependency %s of %s: %w", dep, appName, err)
+			break
+		}
+	}
+	if err != nil {
+		m.mu.Lock()
+		app.Starting = false
+		m.mu.Unlock()
+		return err
+	}
+	return m.launchStarted(app, cfg)
+}
+
+// ensureReady starts a dependency unless it is already running or starting,
+// and waits up to defaultWaitHealthyTimeout for it to become ready (see
+// waitReady). A dependency that is ready and passes its last health check
+// counts as ready right away.
+func (m *Manager) ensureReady(appName string, chain []string) error {
+	if slices.Contains(chain, appName) {
+		return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(chain, " -> "), appName)
+	}
+
+	m.mu.RLock()
+	app, ok := m.apps[appName]
+	if !ok {
+		m.mu.RUnlock()
+		return fmt.Errorf("app %s not found", appName)
+	}
+	ready := app.isReady(time.Now()) && (app.Config.liveURL() == "" || passesHealth(app.HealthStatus))
+	busy := app.Running || app.Starting
+	m.mu.RUnlock()
+	if ready {
+		return nil
+	}
+
+	if !busy {
+		if err := m.startWithDependencies(appName, chain); err != nil {
+			m.mu.RLock()
+			busy = app.Running || app.Starting // Started by someone else meanwhile
+			m.mu.RUnlock()
+			if !busy {
+				return err
+			}
+		} else {
+			appLogf(m.appConfig(appName), "Started %s for %s, which depends on it", appName, chain[len(chain)-1])
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultWaitHealthyTimeout)
+	defer cancel()
+	return m.waitReady(ctx, appName)
+}
+
+// dependencyProblems checks that every depends_on entry names a configured
+// app and that no app depends on itself through a cycle, returning the
+// problems found per app
+func dependencyProblems(configs []AppConfig) map[string][]string {
+	deps := make(map[string][]string, len(configs))
+	for _, cfg := range configs {
+		deps[cfg.Name] = cfg.DependsOn
+	}
+
+	problems := map[string][]string{}
+	for _, cfg := range configs {
+		for _, dep := range cfg.DependsOn {
+			if _, ok := deps[dep]; !ok {
+				problems[cfg.Name] = append(problems[cfg.Name], fmt.Sprintf("depends_on: unknown app %s", dep))
+			}
+		}
+	}
+
+	// Depth-first search; an app reached again while still on the path closes
+	// a cycle, reported once against the app it starts from
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = onPath
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			switch state[dep] {
+			case onPath:
+				i := slices.Index(path, dep)
+				cycle := strings.Join(append(slices.Clone(path[i:]), dep), " -> ")
+				problems[dep] = append(problems[dep], "depends_on: dependency cycle "+cycle)
+			case unvisited:
+				if _, ok := deps[dep]; ok {
+					visit(dep)
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+	}
+	for _, cfg := range configs {
+		if state[cfg.Name] == unvisited {
+			visit(cfg.Name)
+		}
+	}
+	return problems
+}
+
+// DependentsError refuses to stop an app that running apps depend on
+type DependentsError struct {
+	App        string
+	Dependents []string // Running apps that depend on App, directly or not
+}
+
+func (e *DependentsError) Error() string {
+	return fmt.Sprintf("app %s has running dependents: %s", e.App, strings.Join(e.Dependents, ", "))
+}
+
+// runningDependents returns the running apps that depend on appName,
+// directly or through other apps, each before the apps it depends on
+func (m *Manager) runningDependents(appName string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := sortedAppNames(m.apps)
+	var order []string
+	seen := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		seen[name] = true
+		for _, other := range names {
+			if !seen[other] && slices.Contains(m.apps[other].Config.DependsOn, name) {
+				visit(other)
+			}
+		}
+		if name != appName && m.apps[name].Running {
+			order = append(order, name)
+		}
+	}
+	if _, ok := m.apps[appName]; ok {
+		visit(appName)
+	}
+	return order
+}
+
+// StopWithDependents stops an app that other running apps may depend on.
+// With cascade those dependents are stopped first, each before the apps it
+// depends on; without it the stop is refused with a *DependentsError.
+func (m *Manager) StopWithDependents(appName string, cascade bool) error {
+	dependents := m.runningDependents(appName)
+	if len(dependents) > 0 && !cascade {
+		return &DependentsError{App: appName, Dependents: dependents}
+	}
+	for _, name := range dependents {
+		if err := m.StopApp(name); err != nil {
+			return fmt.Errorf("stopping dependent %s: %w", name, err)
+		}
+		appLogf(m.appConfig(name), "Stopped %s, which depends on %s", name, appName)
+	}
+	return m.StopApp(appName)
+}
+
+// stopTiers groups apps for stopping: each tier holds the apps none of the
+// later tiers depend on, directly or not, so stopping tier by tier takes
+// every dependent down before the apps it depends on. Names are sorted
+// within each tier.
+func (m *Manager) stopTiers(names []string) [][]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	selected := map[string]bool{}
+	for _, name := range names {
+		if _, ok := m.apps[name]; ok {
+			selected[name] = true
+		}
+	}
+	// An app's depth is the longest chain of selected apps depending on it
+	depth := map[string]int{}
+	var depthOf func(name string, chain []string) int
+	depthOf = func(name string, chain []string) int {
+		if d, ok := depth[name]; ok {
+			return d
+		}
+		if slices.Contains(chain, name) {
+			return 0 // A cycle, which validation reports; stop it with the rest
+		}
+		d := 0
+		for other := range selected {
+			if slices.Contains(m.apps[other].Config.DependsOn, name) {
+				d = max(d, depthOf(other, append(chain, name))+1)
+			}
+		}
+		depth[name] = d
+		return d
+	}
+
+	var tiers [][]string
+	for _, name := range sortedAppNames(m.apps) {
+		if !selected[name] {
+			continue
+		}
+		d := depthOf(name, nil)
+		for len(tiers) <= d {
+			tiers = append(tiers, nil)
+		}
+		tiers[d] = append(tiers[d], name)
+	}
+	return tiers
+}
+
+// StopOrdered stops apps tier by tier (see stopTiers), so nothing is left
+// running without the apps it depends on. Returns a result per app; apps
+// not reached before ctx is done are reported as timed out.
+func (m *Manager) StopOrdered(ctx context.Context, names []string) []BulkResult {
+	stop := func(_ context.Context, name string) error { return m.StopApp(name) }
+	var results []BulkResult
+	for _, tier := range m.stopTiers(names) {
+		if ctx.Err() != nil {
+			for _, name := range tier {
+				results = append(results, BulkResult{App: name, TimedOut: true, Error: "timed out"})
+			}
+			continue
+		}
+		results = append(results, runBulk(ctx, tier, stop)...)
+	}
+	return results
+}
+
+// sortedAppNames returns the names of apps in order. Must be called with
+// m.mu held.
+func sortedAppNames(apps map[string]*AppState) []string {
+	names := make([]string, 0, len(apps))
+	for name := range apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStopTiersStopDependentsFirst(t *testing.T) {
+	// web -> api -> db, worker -> db; cache stands alone
+	m := newTestManager(t,
+		AppConfig{Name: "db"},
+		AppConfig{Name: "api", DependsOn: []string{"db"}},
+		AppConfig{Name: "web", DependsOn: []string{"api"}},
+		AppConfig{Name: "worker", DependsOn: []string{"db"}},
+		AppConfig{Name: "cache"},
+	)
+	want := [][]string{{"cache", "web", "worker"}, {"api"}, {"db"}}
+	if got := m.stopTiers([]string{"api", "cache", "db", "web", "worker"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("stopTiers = %v, want %v", got, want)
+	}
+
+	// Apps that aren't being stopped don't hold anything back
+	want = [][]string{{"api", "worker"}, {"db"}}
+	if got := m.stopTiers([]string{"api", "db", "worker"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("stopTiers without web = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultDeployTimeout is how long a freshly deployed binary has to become healthy
+const defaultDeployTimeout = 30 * time.Second
+
+// deploySettle is how long an app without a HealthURL must stay up to count as deployed
+const deploySettle = 2 * time.Second
+
+// DeployResult reports the outcome of a binary deploy
+type DeployResult struct {
+	App        string `json:"app"`
+	OK         bool   `json:"ok"`
+	RolledBack bool   `json:"rolled_back"`
+	Error      string `json:"error,omitempty"`
+}
+
+// stageBinary copies src into a temp file next to dest so it can be renamed
+// over dest atomically
+func stageBinary(src io.Reader, dest string) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".deploy-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Chmod(0755); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// waitReady waits for an app's current run to pass its readiness probe, or
+// for apps without one, to still be running after a short settle period
+func (m *Manager) waitReady(ctx context.Context, appName string) error {
+	m.mu.RLock()
+	app, ok := m.apps[appName]
+	hasProbe := ok && app.Config.readyURL() != ""
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("app %s not found", appName)
+	}
+	if hasProbe {
+		return m.waitReadyProbe(ctx, app)
+	}
+
+	select {
+	case <-time.After(deploySettle):
+	case <-ctx.Done():
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !app.Running {
+		return fmt.Errorf("app %s exited after starting", appName)
+	}
+	return nil
+}
+
+// waitReadyProbe waits for the readiness gate of an app's current run
+func (m *Manager) waitReadyProbe(ctx context.Context, app *AppState) error {
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+	for {
+		m.mu.RLock()
+		ready, running, status := app.Ready, app.Running || app.Starting, app.HealthStatus
+		m.mu.RUnlock()
+		if ready {
+			return nil
+		}
+		if !running {
+			return fmt.Errorf("app %s exited before becoming ready (status: %s)", app.Config.Name, status)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("app %s did not become ready: %w", app.Config.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeployBinary atomically swaps in the staged binary at staged, restarts the
+// app, and rolls back to the previous binary if it doesn't become ready. The
+// previous binary is removed once the new one is ready. The caller must hold
+// the app's deployMu.
+func (m *Manager) DeployBinary(ctx context.Context, appName, staged string) DeployResult {
+	result := DeployResult{App: appName}
+	m.mu.RLock()
+	app, ok := m.apps[appName]
+	var path string
+	if ok {
+		path = app.Config.binaryPath()
+	}
+	m.mu.RUnlock()
+	if !ok {
+		result.Error = fmt.Sprintf("app %s not found", appName)
+		return result
+	}
+
+	// Keep the current binary so we can roll back to it
+	prev, err := swapBinary(path, staged)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	log.Printf("Deployed new binary for %s at %s", appName, path)
+
+	err = m.RestartApp(appName, RestartDeploy)
+	if err == nil {
+		err = m.waitReady(ctx, appName)
+	}
+	if err == nil {
+		os.Remove(prev)
+		result.OK = true
+		return result
+	}
+
+	result.Error = err.Error()
+	log.Printf("Deploy of %s failed, rolling back: %v", appName, err)
+	if rerr := os.Rename(prev, path); rerr != nil {
+		result.Error += fmt.Sprintf("; rollback failed: %v", rerr)
+		return result
+	}
+	if rerr := m.RestartApp(appName, RestartRollback); rerr != nil {
+		result.Error += fmt.Sprintf("; restart after rollback failed: %v", rerr)
+		return result
+	}
+	result.RolledBack = true
+	return result
+}
+
+// deployHandler deploys a new binary for an app. The binary is either the
+// request body or an already staged file given by the path query param.
+// The optional timeout query param bounds the readiness check. Running apps
+// with a DeployPort are deployed blue/green. Concurrent deploys of an app
+// wait their turn.
+func deployHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	mgr.mu.RLock()
+	app, ok := mgr.apps[appName]
+	var dest string
+	var shell bool
+	if ok {
+		dest = app.Config.binaryPath()
+		shell = app.Config.Shell
+	}
+	mgr.mu.RUnlock()
+	if !ok {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+	if shell {
+		http.Error(w, "Shell apps have no binary to deploy", http.StatusBadRequest)
+		return
+	}
+
+	timeout := defaultDeployTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("Invalid timeout %q", v), http.StatusBadRequest)
+			return
+		}
+		timeout = d
+	}
+
+	src := io.Reader(r.Body)
+	if p := r.URL.Query().Get("path"); p != "" {
+		f, err := os.Open(p)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to open staged binary: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		src = f
+	}
+	staged, err := stageBinary(src, dest)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to stage binary for %s: %v", appName, err), http.StatusInternalServerError)
+		return
+	}
+
+	app.deployMu.Lock() // Waits out any deploy of the app already under way
+	defer app.deployMu.Unlock()
+	mgr.mu.RLock()
+	blueGreen := app.Config.alternate != nil && app.Running
+	mgr.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	deploy := mgr.DeployBinary
+	if blueGreen {
+		deploy = mgr.BlueGreenDeploy
+	}
+	result := deploy(ctx, appName, staged)
+
+	w.Header().Set("Content-Type", "application/json")
+	if !result.OK {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding deploy result for %s: %v", appName, err)
+	}
+}
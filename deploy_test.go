@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeployWaitsForAppDeployAndRemovesPrev(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "app")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\nexec sleep 60\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestManager(t, AppConfig{Name: "app", Path: bin})
+	if err := m.StartApp("app"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stand in for a deploy already under way
+	app := m.apps["app"]
+	app.deployMu.Lock()
+	next := "#!/bin/sh\n# v2\nexec sleep 60\n"
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		r := httptest.NewRequest("POST", "/api/app/app/deploy", strings.NewReader(next))
+		r.SetPathValue("name", "app")
+		w := httptest.NewRecorder()
+		deployHandler(m, w, r)
+		done <- w
+	}()
+	time.Sleep(200 * time.Millisecond)
+	if data, _ := os.ReadFile(bin); string(data) == next {
+		t.Fatal("binary swapped while another deploy of the app held its lock")
+	}
+	app.deployMu.Unlock()
+
+	w := <-done
+	if w.Code != http.StatusOK {
+		t.Fatalf("deploy: %d %s", w.Code, w.Body)
+	}
+	if data, _ := os.ReadFile(bin); string(data) != next {
+		t.Errorf("binary after deploy = %q, want the deployed one", data)
+	}
+	if _, err := os.Stat(bin + ".prev"); !os.IsNotExist(err) {
+		t.Errorf("previous binary left behind after a successful deploy: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// outputFollowInterval is how often a detached app's output file is checked
+// for new output once albert has caught up with it
+const outputFollowInterval = 200 * time.Millisecond
+
+// detachedOutput returns the file an app with on_manager_exit: detach writes
+// its output to, or "" for an app whose output goes through pipes. Pipes
+// would be gone once albert exits, leaving the app with nowhere to write.
+func (m *Manager) detachedOutput(cfg AppConfig) string {
+	if policy, err := cfg.managerExitPolicy(); err != nil || policy != ManagerExitDetach {
+		return ""
+	}
+	if cfg.OutputFile != "" {
+		return cfg.OutputFile
+	}
+	dir := os.TempDir()
+	if m.stateFile != "" {
+		dir = filepath.Dir(m.stateFile)
+	}
+	return filepath.Join(dir, "albert-"+cfg.Name+".out")
+}
+
+// outputFollower reads an output file as a process appends to it, like
+// tail -f, until the process has exited and everything it wrote is read
+type outputFollower struct {
+	f         *os.File
+	pid       int
+	startTime uint64 // Tells the process apart from a later one reusing its pid
+}
+
+// followOutput opens path for following from its current end, so only
+// output written from now on is read. Call watch once the process writing
+// to it is known.
+func followOutput(path string) (*outputFollower, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &outputFollower{f: f}, nil
+}
+
+// watch sets the process whose exit ends the output
+func (o *outputFollower) watch(pid int) {
+	o.pid = pid
+	o.startTime, _ = procStartTime(pid) // Zero, and so gone, if it already exited
+}
+
+// gone reports whether the writing process has exited
+func (o *outputFollower) gone() bool {
+	start, err := procStartTime(o.pid)
+	return err != nil || start != o.startTime
+}
+
+func (o *outputFollower) Read(p []byte) (int, error) {
+	for {
+		n, err := o.f.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		if o.gone() {
+			// Whatever it wrote before exiting is in the file by now
+			if n, err = o.f.Read(p); err == io.EOF {
+				o.f.Close()
+			}
+			return n, err
+		}
+		if offset, err := o.f.Seek(0, io.SeekCurrent); err == nil {
+			if st, err := o.f.Stat(); err == nil && st.Size() < offset {
+				o.f.Seek(0, io.SeekStart) // Truncated, e.g. by logrotate's copytruncate
+			}
+		}
+		time.Sleep(outputFollowInterval)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Discovery registers every tool found under a base directory: each
+// subdirectory holding an executable of the same name, like
+// /home/tommy/heckler/heckler, becomes an app named after it that runs in
+// that subdirectory
+type Discovery struct {
+	Dir      string    `json:"dir"`      // Base directory; relative to the config file
+	Defaults AppConfig `json:"defaults"` // Settings for every discovered app; may use {{.Name}}
+	Exclude  []string  `json:"exclude"`  // Subdirectories to skip
+}
+
+// discoverApps scans each discovery's base directory, in name order.
+// configDir resolves relative base directories.
+func discoverApps(discoveries []Discovery, configDir string) ([]AppConfig, error) {
+	var apps []AppConfig
+	for _, d := range discoveries {
+		dir := d.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(configDir, dir)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s for apps: %w", dir, err)
+		}
+		excluded := make(map[string]bool, len(d.Exclude))
+		for _, name := range d.Exclude {
+			excluded[name] = true
+		}
+
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if e.IsDir() && !strings.HasPrefix(e.Name(), ".") && !excluded[e.Name()] {
+				names = append(names, e.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			bin := filepath.Join(dir, name, name)
+			st, err := os.Stat(bin)
+			if err != nil || !st.Mode().IsRegular() || st.Mode().Perm()&0o111 == 0 {
+				continue
+			}
+			cfg := d.Defaults
+			cfg.Name = name
+			cfg.Path = bin
+			cfg.WorkDir = filepath.Join(dir, name)
+			apps = append(apps, cfg)
+		}
+	}
+	return apps, nil
+}
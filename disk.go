@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+)
+
+// diskPath returns the filesystem path whose free space gates the app,
+// defaulting to the directory holding its binary (the directory it runs in
+// for shell apps)
+func (cfg AppConfig) diskPath() string {
+	if cfg.DiskPath != "" {
+		return cfg.DiskPath
+	}
+	if cfg.Shell {
+		if cfg.WorkDir != "" {
+			return cfg.WorkDir
+		}
+		return "."
+	}
+	return filepath.Dir(cfg.binaryPath())
+}
+
+// freeDiskMB returns the space available to unprivileged users on the
+// filesystem containing path, in megabytes
+func freeDiskMB(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return st.Bavail * uint64(st.Bsize) / (1024 * 1024), nil
+}
+
+// checkDiskSpace returns an error if the app's disk is below MinFreeDiskMB
+func checkDiskSpace(cfg AppConfig) error {
+	if cfg.MinFreeDiskMB <= 0 {
+		return nil
+	}
+	path := cfg.diskPath()
+	free, err := freeDiskMB(path)
+	if err != nil {
+		return fmt.Errorf("failed to check free disk space on %s for %s: %w", path, cfg.Name, err)
+	}
+	if free < uint64(cfg.MinFreeDiskMB) {
+		return fmt.Errorf("app %s needs %d MB free on %s but only %d MB is available", cfg.Name, cfg.MinFreeDiskMB, path, free)
+	}
+	return nil
+}
+
+// updateDiskStatus re-checks free space for an app and flags it as DiskLow,
+// logging when the state changes
+func (m *Manager) updateDiskStatus(app *AppState) {
+	m.mu.RLock()
+	cfg := app.Config
+	m.mu.RUnlock()
+	if cfg.MinFreeDiskMB <= 0 {
+		return
+	}
+
+	err := checkDiskSpace(cfg)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	low := err != nil
+	if low && !app.DiskLow {
+		appLogf(cfg, "Disk space low for %s, starts are blocked: %v", cfg.Name, err)
+	} else if !low && app.DiskLow {
+		appLogf(cfg, "Disk space recovered for %s", cfg.Name)
+	}
+	app.DiskLow = low
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// appEnv returns the environment for an app's process: albert's own
+// environment, then the app's EnvFile, then its Env, later ones winning
+func appEnv(cfg AppConfig) ([]string, error) {
+	env := os.Environ()
+	if cfg.EnvFile != "" {
+		fileEnv, err := readEnvFile(cfg.EnvFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read env file for %s: %w", cfg.Name, err)
+		}
+		env = append(env, fileEnv...)
+	}
+	keys := make([]string, 0, len(cfg.Env))
+	for k := range cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+cfg.Env[k]) // exec.Cmd keeps the last value for duplicate keys
+	}
+	return env, nil
+}
+
+// readEnvFile parses a .env file of KEY=value lines. Blank lines and lines
+// starting with # are skipped, an "export " prefix is allowed, and values
+// may be wrapped in single or double quotes. Nothing is expanded.
+func readEnvFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var env []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, ok := strings.Cut(line, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=value", path, n)
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		env = append(env, k+"="+v)
+	}
+	return env, scanner.Err()
+}
+
+// missingEnv returns the names in required that are unset or empty in env,
+// where env is in the KEY=value form used by os.Environ and exec.Cmd.Env
+func missingEnv(required []string, env []string) []string {
+	if len(required) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(env))
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			set[k] = v != "" // Later entries win, as in exec.Cmd
+		}
+	}
+	var missing []string
+	for _, name := range required {
+		if !set[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/RoughCookiexx/gg_sse"
+)
+
+// Restart reasons carried by restart events
+const (
+	RestartManual       = "manual"
+	RestartUnhealthy    = "unhealthy"
+	RestartDeploy       = "deploy"
+	RestartRollback     = "rollback"
+	RestartConfigChange = "config_change"
+	RestartCrashed      = "crashed" // Restart policy, after a crash
+	RestartExited       = "exited"  // Restart policy "always", after a clean exit
+	RestartScheduled    = "scheduled"
+	RestartBinaryChange = "binary_change"
+	RestartRolling      = "rolling"
+	RestartLiveness     = "liveness" // LiveFailures failed liveness checks in a row
+)
+
+// RestartEvent is sent over SSE each time albert restarts an app
+type RestartEvent struct {
+	Type   string    `json:"type"`
+	App    string    `json:"app"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// CrashEvent is sent over SSE when an app exits with an error, subject to
+// the app's CrashAlertInterval
+type CrashEvent struct {
+	Type       string    `json:"type"`
+	App        string    `json:"app"`
+	Error      string    `json:"error"`
+	Severity   string    `json:"severity"`
+	Suppressed int       `json:"suppressed"` // Crashes since the previous alert that weren't announced
+	Time       time.Time `json:"time"`
+}
+
+// emitEvent sends an event to SSE subscribers as JSON. It may be batched
+// with other events sent around the same time.
+func emitEvent(event any) {
+	if b, ok := encodeEvent(event); ok {
+		sseEvents.Send(b)
+	}
+}
+
+// emitCriticalEvent sends an event to SSE subscribers right away, bypassing
+// batching. It can arrive ahead of batched events that happened earlier.
+func emitCriticalEvent(event any) {
+	if b, ok := encodeEvent(event); ok {
+		sse.SendBytes(b)
+	}
+}
+
+// encodeEvent marshals an event, logging any failure
+func encodeEvent(event any) ([]byte, bool) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding event: %v", err)
+		return nil, false
+	}
+	return b, true
+}
+
+// recordRestart counts a restart by reason and announces it to subscribers
+func (m *Manager) recordRestart(appName, reason string) {
+	m.mu.Lock()
+	if app, ok := m.apps[appName]; ok {
+		if app.Restarts == nil {
+			app.Restarts = make(map[string]int)
+		}
+		app.Restarts[reason]++
+		app.RestartCount++
+		app.lastRestart = time.Now()
+	}
+	m.mu.Unlock()
+
+	event := RestartEvent{Type: "app_restarted", App: appName, Reason: reason, Time: time.Now()}
+	emitEvent(event)
+	hub.Publish(EventRestart, event)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types published on /events
+const (
+	EventChat       = "chat"       // Twitch chat message
+	EventAppState   = "app_state"  // App started or stopped
+	EventAppOutput  = "app_output" // Chunk or structured line of app output
+	EventAppExited  = "app_exited" // App process exited on its own
+	EventHealth     = "health"     // Health check outcome
+	EventRestart    = "restart"    // Restart performed by albert
+	EventCrash      = "crash"      // Crash alert, subject to CrashAlertInterval
+	EventQuarantine = "quarantine" // App quarantined for crash-looping
+
+	EventConfigChanged = "config_changed" // Config file changed on disk; data is a ConfigDiff
+)
+
+const (
+	defaultMaxEventClients = 64
+	eventClientBuffer      = 256              // Events queued per client before new ones are dropped
+	eventKeepAlive         = 30 * time.Second // Comment lines keep idle connections open
+	eventRetryAfter        = "5"              // Seconds, sent with 503 when the hub is full
+)
+
+// HubEvent is the JSON payload of every event on /events. IDs increase by one
+// per published event, so a gap means the client dropped events.
+type HubEvent struct {
+	ID   uint64    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// AppStateEvent is the data of an app_state event
+type AppStateEvent struct {
+	App          string `json:"app"`
+	Running      bool   `json:"running"`
+	HealthStatus string `json:"health_status"`
+}
+
+// AppOutputEvent is the data of an app_output event
+type AppOutputEvent struct {
+	App    string `json:"app"`
+	Output string `json:"output"`
+}
+
+// AppExitedEvent is the data of an app_exited event
+type AppExitedEvent struct {
+	App   string `json:"app"`
+	Error string `json:"error,omitempty"` // Empty for a clean exit
+}
+
+// HealthEvent is the data of a health event
+type HealthEvent struct {
+	App       string `json:"app"`
+	Status    string `json:"status"`
+	Code      int    `json:"code"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// stateEvent describes the app's current state. Must be called with m.mu held.
+func (app *AppState) stateEvent() AppStateEvent {
+	return AppStateEvent{App: app.Config.Name, Running: app.Running, HealthStatus: app.HealthStatus}
+}
+
+// hubEvent is an encoded event ready to write to clients
+type hubEvent struct {
+	id   uint64
+	data []byte
+}
+
+// hubClient is one connected /events stream
+type hubClient struct {
+	types  map[string]bool // Empty means every type
+	events chan hubEvent
+}
+
+// eventHub fans published events out to /events clients
+type eventHub struct {
+	mu         sync.Mutex
+	nextID     uint64
+	clients    map[*hubClient]struct{}
+	maxClients int
+}
+
+// hub carries albert's typed event stream
+var hub = &eventHub{clients: make(map[*hubClient]struct{}), maxClients: defaultMaxEventClients}
+
+// SetMaxClients caps concurrent /events connections
+func (h *eventHub) SetMaxClients(max int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxClients = max
+}
+
+// Stats returns the number of connected clients and the cap
+func (h *eventHub) Stats() (clients, max int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients), h.maxClients
+}
+
+// Publish sends an event to every client subscribed to its type. It never
+// blocks: clients that have fallen behind miss the event.
+func (h *eventHub) Publish(typ string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	if len(h.clients) == 0 {
+		return
+	}
+	b, err := json.Marshal(HubEvent{ID: h.nextID, Type: typ, Time: time.Now(), Data: data})
+	if err != nil {
+		log.Printf("Error encoding %s event: %v", typ, err)
+		return
+	}
+	event := hubEvent{id: h.nextID, data: b}
+	for c := range h.clients {
+		if len(c.types) > 0 && !c.types[typ] {
+			continue
+		}
+		select {
+		case c.events <- event:
+		default:
+		}
+	}
+}
+
+// subscribe registers a client for the given types, or all types if none.
+// It fails once maxClients are connected.
+func (h *eventHub) subscribe(types []string) (*hubClient, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.maxClients > 0 && len(h.clients) >= h.maxClients {
+		return nil, false
+	}
+	c := &hubClient{types: make(map[string]bool), events: make(chan hubEvent, eventClientBuffer)}
+	for _, t := range types {
+		c.types[t] = true
+	}
+	h.clients[c] = struct{}{}
+	return c, true
+}
+
+// unsubscribe removes a client
+func (h *eventHub) unsubscribe(c *hubClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, c)
+}
+
+// eventsHandler streams albert's events as SSE. Each message's data is a
+// HubEvent whose id matches the SSE id. Messages are unnamed so
+// EventSource.onmessage sees them all; switch on the type field instead.
+// ?types= takes a comma separated list of event types to receive; all are
+// sent without it.
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var types []string
+	for _, t := range strings.Split(r.URL.Query().Get("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+
+	client, ok := hub.subscribe(types)
+	if !ok {
+		w.Header().Set("Retry-After", eventRetryAfter)
+		http.Error(w, "Too many event stream clients", http.StatusServiceUnavailable)
+		return
+	}
+	defer hub.unsubscribe(client)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-client.events:
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.id, event.data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// exitHistoryLimit is how many exits are kept per app
+const exitHistoryLimit = 50
+
+// ExitRecord is one exit of an app's process
+type ExitRecord struct {
+	Time     time.Time `json:"time"`
+	ExitCode *int      `json:"exit_code"`        // -1 if killed by a signal, null if unknown
+	Signal   string    `json:"signal,omitempty"` // Signal that killed it, if any
+	Ran      Duration  `json:"ran"`              // How long the run lasted
+	Error    string    `json:"error,omitempty"`  // Empty for a clean exit
+}
+
+// recordExit notes a run that started at started and exited with err, as
+// the app's LastExitCode and in its exit history. Must be called with m.mu
+// held.
+func (app *AppState) recordExit(err error, started time.Time) {
+	now := time.Now()
+	record := ExitRecord{Time: now, ExitCode: exitCode(err), Ran: Duration(now.Sub(started).Round(time.Millisecond))}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			record.Signal = signalName(status.Signal())
+		}
+	}
+	app.LastExitCode = record.ExitCode
+	app.exits = append(app.exits, record)
+	if len(app.exits) > exitHistoryLimit {
+		app.exits = append([]ExitRecord(nil), app.exits[len(app.exits)-exitHistoryLimit:]...)
+	}
+}
+
+// getExitsHandler returns an app's recent exits, oldest first
+func getExitsHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	mgr.mu.RLock()
+	app, ok := mgr.apps[appName]
+	exits := []ExitRecord{}
+	if ok {
+		exits = append(exits, app.exits...)
+	}
+	mgr.mu.RUnlock()
+
+	if !ok {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(exits); err != nil {
+		log.Printf("Error encoding exit history for %s: %v", appName, err)
+	}
+}
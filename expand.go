@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// configVar matches ${VAR} references in config values. Bare $VAR is left
+// alone so values meant for a shell or the app itself pass through.
+var configVar = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandVars replaces ${VAR} references in s with albert's environment
+func expandVars(s string) (string, error) {
+	var missing string
+	out := configVar.ReplaceAllStringFunc(s, func(ref string) string {
+		name := configVar.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok && missing == "" {
+			missing = name
+		}
+		return v
+	})
+	if missing != "" {
+		return "", fmt.Errorf("%q references unset variable %s", s, missing)
+	}
+	return out, nil
+}
+
+// expandConfig fills in {{...}} templates and then expands ${VAR}
+// references in an app's Path, Args, health URLs, EnvFile, WorkDir and Env
+// values. Shell apps keep their Path as written, since the shell expands it
+// with the app's own environment. Apps with a DeployPort are also expanded
+// for it, for blue/green deploys.
+func expandConfig(cfg AppConfig) (AppConfig, error) {
+	expanded, err := expandFields(cfg)
+	if err != nil || cfg.DeployPort <= 0 {
+		return expanded, err
+	}
+	swapped := cfg
+	swapped.Port, swapped.DeployPort = cfg.DeployPort, cfg.Port
+	alternate, err := expandFields(swapped)
+	if err != nil {
+		return AppConfig{}, err
+	}
+	expanded.alternate = &alternate
+	return expanded, nil
+}
+
+// expandFields does the work of expandConfig for a single port
+func expandFields(cfg AppConfig) (AppConfig, error) {
+	var err error
+	expand := func(s string) string {
+		if err != nil {
+			return s
+		}
+		var out string
+		if out, err = renderTemplate(s, cfg); err != nil {
+			return s
+		}
+		out, err = expandVars(out)
+		return out
+	}
+
+	if !cfg.Shell {
+		cfg.Path = expand(cfg.Path)
+	}
+	if cfg.Args != nil {
+		args := make([]string, len(cfg.Args))
+		for i, arg := range cfg.Args {
+			args[i] = expand(arg)
+		}
+		cfg.Args = args
+	}
+	cfg.HealthURL = expand(cfg.HealthURL)
+	cfg.ReadyURL = expand(cfg.ReadyURL)
+	cfg.LiveURL = expand(cfg.LiveURL)
+	cfg.EnvFile = expand(cfg.EnvFile)
+	cfg.WorkDir = expand(cfg.WorkDir)
+	if cfg.Env != nil {
+		env := make(map[string]string, len(cfg.Env))
+		for k, v := range cfg.Env {
+			env[k] = expand(v)
+		}
+		cfg.Env = env
+	}
+	if err != nil {
+		return AppConfig{}, fmt.Errorf("app %s: %w", cfg.Name, err)
+	}
+	return cfg, nil
+}
+
+// expandConfigs expands every app's config, failing on the first error
+func expandConfigs(configs []AppConfig) ([]AppConfig, error) {
+	expanded := make([]AppConfig, len(configs))
+	for i, cfg := range configs {
+		var err error
+		if expanded[i], err = expandConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runExport implements "albert export systemd": it writes a systemd service
+// unit for each app albert would manage, to --out or stdout. Returns the
+// exit code.
+func runExport(args []string) int {
+	if len(args) == 0 || args[0] != "systemd" {
+		fmt.Fprintln(os.Stderr, "usage: albert export systemd [--config file] [--profile name] [--out dir]")
+		return 2
+	}
+	fs := flag.NewFlagSet("export systemd", flag.ContinueOnError)
+	configPath := fs.String("config", "", "export the apps in this config file instead of the built-in list")
+	profile := fs.String("profile", "", "apply this profile from the config file")
+	out := fs.String("out", "", "write one <app>.service file per app into this directory (default stdout)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	configs, err := loadStartupConfigs(*configPath, *profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+	for _, cfg := range configs {
+		unit := systemdUnitName(cfg.Name)
+		if usesSecrets(cfg) {
+			fmt.Fprintf(os.Stderr, "Warning: %s uses secret:// references, which %s leaves unresolved\n", cfg.Name, unit)
+		}
+		if cfg.EnvFile != "" {
+			if cfg, err = exportEnvFile(cfg, *out); err != nil {
+				fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+				return 1
+			}
+		}
+		if *out == "" {
+			fmt.Printf("# %s\n%s\n", unit, systemdUnit(cfg))
+			continue
+		}
+		path := filepath.Join(*out, unit)
+		if err := os.WriteFile(path, []byte(systemdUnit(cfg)), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	}
+	return 0
+}
+
+// unitNameChars matches characters that don't belong in a unit name
+var unitNameChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// systemdUnitName turns an app name into a unit file name, e.g.
+// "K Facts" into "k-facts.service"
+func systemdUnitName(appName string) string {
+	name := strings.Trim(unitNameChars.ReplaceAllString(strings.ToLower(appName), "-"), "-")
+	if name == "" {
+		name = "app"
+	}
+	return name + ".service"
+}
+
+// exportEnvFile rewrites an app's env file for systemd, whose
+// EnvironmentFile= takes neither albert's "export " prefix nor its
+// unescaped quoting. With outDir the rewritten file is written next to the
+// unit, readable only by its owner since it may hold secrets; without one
+// its variables are moved into Env so the unit carries them itself.
+func exportEnvFile(cfg AppConfig, outDir string) (AppConfig, error) {
+	fileEnv, err := readEnvFile(cfg.EnvFile)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read env file for %s: %w", cfg.Name, err)
+	}
+	if outDir == "" {
+		env := make(map[string]string, len(fileEnv)+len(cfg.Env))
+		for _, kv := range fileEnv {
+			k, v, _ := strings.Cut(kv, "=")
+			env[k] = v
+		}
+		for k, v := range cfg.Env {
+			env[k] = v // Env overrides the env file, as when albert runs the app
+		}
+		cfg.Env, cfg.EnvFile = env, ""
+		return cfg, nil
+	}
+
+	var b strings.Builder
+	for _, kv := range fileEnv {
+		k, v, _ := strings.Cut(kv, "=")
+		fmt.Fprintf(&b, "%s=%s\n", k, systemdEnvQuote(v))
+	}
+	path, err := filepath.Abs(filepath.Join(outDir, strings.TrimSuffix(systemdUnitName(cfg.Name), ".service")+".env"))
+	if err == nil {
+		err = os.WriteFile(path, []byte(b.String()), 0o600)
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to write env file for %s: %w", cfg.Name, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	cfg.EnvFile = path
+	return cfg, nil
+}
+
+// systemdUnit renders a service unit that runs an app the way albert would:
+// same command, directory, user, environment, sandbox root, open file limit
+// and dependencies, restarted per its restart policy (on failure without one)
+func systemdUnit(cfg AppConfig) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\nDescription=%s (exported from albert)\nAfter=network-online.target\nWants=network-online.target\n", systemdEscape(cfg.Name))
+	for _, dep := range cfg.DependsOn {
+		fmt.Fprintf(&b, "Requires=%[1]s\nAfter=%[1]s\n", systemdUnitName(dep))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("[Service]\nType=simple\n")
+	cmd := appCommand(cfg)
+	words := make([]string, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		words[i] = systemdQuote(arg, true)
+	}
+	words[0] = systemdQuote(cmd.Path, true) // Args[0] is the name the command was given; Path is what runs
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(words, " "))
+	if cfg.WorkDir != "" {
+		fmt.Fprintf(&b, "WorkingDirectory=%s\n", systemdEscape(cfg.WorkDir))
+	}
+	if cfg.User != "" {
+		fmt.Fprintf(&b, "User=%s\n", cfg.User)
+	}
+	if cfg.Group != "" {
+		fmt.Fprintf(&b, "Group=%s\n", cfg.Group)
+	}
+	if cfg.EnvFile != "" {
+		fmt.Fprintf(&b, "EnvironmentFile=%s\n", systemdEscape(cfg.EnvFile))
+	}
+	keys := make([]string, 0, len(cfg.Env))
+	for k := range cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(k+"="+cfg.Env[k], false))
+	}
+	if cfg.Chroot != "" {
+		fmt.Fprintf(&b, "RootDirectory=%s\n", systemdEscape(cfg.Chroot))
+	}
+	if cfg.MaxOpenFiles > 0 {
+		fmt.Fprintf(&b, "LimitNOFILE=%d\n", cfg.MaxOpenFiles)
+	}
+	restart := cfg.Restart
+	switch restart {
+	case "":
+		restart = RestartPolicyOnFailure // Units are worth restarting even where albert wouldn't
+	case RestartPolicyNever:
+		restart = "no"
+	}
+	fmt.Fprintf(&b, "Restart=%s\n", restart)
+	delay := cfg.RestartDelay // Before restarting after a clean exit
+	if restart == RestartPolicyOnFailure || delay == 0 {
+		delay = cfg.RestartBackoff // Only crashes are restarted, or no delay was set
+	}
+	if delay > 0 {
+		fmt.Fprintf(&b, "RestartSec=%s\n", time.Duration(delay))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("[Install]\nWantedBy=multi-user.target\n")
+	return b.String()
+}
+
+// systemdEscape doubles % so systemd doesn't read it as a specifier
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// systemdQuote escapes a word for a unit file, double-quoting it if it
+// contains whitespace, quotes or backslashes. In command lines (exec set) $
+// is doubled too, since systemd would expand it as a variable.
+func systemdQuote(s string, exec bool) string {
+	s = systemdEscape(s)
+	if exec {
+		s = strings.ReplaceAll(s, "$", "$$")
+	}
+	if s != "" && !strings.ContainsAny(s, " \t\n\"'\\;") {
+		return s
+	}
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+// systemdEnvQuote double-quotes a value for an EnvironmentFile= line,
+// escaping what systemd would otherwise take as the end of the value or a
+// line continuation
+func systemdEnvQuote(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(v) + `"`
+}
+
+// usesSecrets reports whether an app's Args or Env name secrets
+func usesSecrets(cfg AppConfig) bool {
+	for _, arg := range cfg.Args {
+		if strings.HasPrefix(arg, secretScheme) {
+			return true
+		}
+	}
+	for _, v := range cfg.Env {
+		if strings.HasPrefix(v, secretScheme) {
+			return true
+		}
+	}
+	return false
+}
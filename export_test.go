@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSystemdUnitRestartSec(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  AppConfig
+		want string
+	}{
+		{AppConfig{Restart: RestartPolicyAlways, RestartDelay: Duration(5 * time.Second), RestartBackoff: Duration(time.Second)}, "RestartSec=5s\n"},
+		{AppConfig{Restart: RestartPolicyOnFailure, RestartDelay: Duration(5 * time.Second), RestartBackoff: Duration(2 * time.Second)}, "RestartSec=2s\n"},
+		{AppConfig{Restart: RestartPolicyAlways, RestartBackoff: Duration(3 * time.Second)}, "RestartSec=3s\n"},
+	} {
+		tc.cfg.Name, tc.cfg.Path = "web", "/usr/bin/web"
+		if unit := systemdUnit(tc.cfg); !strings.Contains(unit, tc.want) {
+			t.Errorf("unit for %+v lacks %q:\n%s", tc.cfg, tc.want, unit)
+		}
+	}
+}
+
+func TestExportEnvFileForSystemd(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "web.env")
+	if err := os.WriteFile(envFile, []byte("export TOKEN=abc\nGREETING='say \"hi\" for $5'\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := AppConfig{Name: "web", Path: "/usr/bin/web", EnvFile: envFile, Env: map[string]string{"TOKEN": "override"}}
+
+	out := t.TempDir()
+	exported, err := exportEnvFile(cfg, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(exported.EnvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "TOKEN=\"abc\"\nGREETING=\"say \\\"hi\\\" for \\$5\"\n"; string(data) != want {
+		t.Errorf("env file = %q, want %q", data, want)
+	}
+	if filepath.Dir(exported.EnvFile) != out {
+		t.Errorf("env file written to %s, want it in %s", exported.EnvFile, out)
+	}
+
+	// Without an output directory the variables go into the unit instead
+	inline, err := exportEnvFile(cfg, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inline.EnvFile != "" || inline.Env["TOKEN"] != "override" || inline.Env["GREETING"] != `say "hi" for $5` {
+		t.Errorf("inlined env file = %q, env %v", inline.EnvFile, inline.Env)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fifoWriter mirrors app output into a named pipe. Writes never block: with
+// no reader attached, or a reader that can't keep up, output is dropped.
+type fifoWriter struct {
+	path string
+	f    *os.File
+}
+
+// newFIFOWriter creates the named pipe at path, reusing an existing one
+func newFIFOWriter(path string) (*fifoWriter, error) {
+	if fi, err := os.Stat(path); err == nil {
+		if fi.Mode()&os.ModeNamedPipe == 0 {
+			return nil, fmt.Errorf("%s exists and is not a FIFO", path)
+		}
+	} else if err := syscall.Mkfifo(path, 0644); err != nil {
+		return nil, fmt.Errorf("failed to create FIFO %s: %w", path, err)
+	}
+	return &fifoWriter{path: path}, nil
+}
+
+// Write sends p to the current reader, connecting to a new reader if needed
+func (fw *fifoWriter) Write(p []byte) (int, error) {
+	if fw.f == nil {
+		// Opening write-only without blocking fails with ENXIO until a reader connects
+		f, err := os.OpenFile(fw.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			if errors.Is(err, syscall.ENXIO) {
+				return len(p), nil
+			}
+			return 0, err
+		}
+		fw.f = f
+	}
+
+	n, err := fw.f.Write(p)
+	if errors.Is(err, syscall.EPIPE) {
+		// Reader went away; reconnect on a later write
+		fw.f.Close()
+		fw.f = nil
+		return len(p), nil
+	}
+	if errors.Is(err, syscall.EAGAIN) {
+		return len(p), nil
+	}
+	return n, err
+}
+
+// Close disconnects any reader and removes the FIFO from disk
+func (fw *fifoWriter) Close() error {
+	if fw.f != nil {
+		fw.f.Close()
+		fw.f = nil
+	}
+	return os.Remove(fw.path)
+}
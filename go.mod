@@ -3,7 +3,26 @@ module github.com/RoughCookiexx/albert
 go 1.22.0
 
 require (
-	github.com/RoughCookiexx/gg_sse v0.0.0-20250603190242-a2b51f479f1e // indirect
-	github.com/RoughCookiexx/gg_twitch_types v0.0.0-20250609233857-77c5dab647a6 // indirect
-	github.com/RoughCookiexx/twitch_chat_subscriber v0.0.0-20250610010439-43558e359a97 // indirect
+	github.com/BurntSushi/toml v1.4.0
+	github.com/RoughCookiexx/gg_sse v0.0.0-20250603190242-a2b51f479f1e
+	github.com/RoughCookiexx/gg_twitch_types v0.0.0-20250609233857-77c5dab647a6
+	github.com/RoughCookiexx/twitch_chat_subscriber v0.0.0-20250610010439-43558e359a97
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/hashicorp/mdns v1.0.6
+	github.com/robfig/cron/v3 v3.0.1
+	google.golang.org/grpc v1.70.0
+	gopkg.in/yaml.v3 v3.0.1
+	sigs.k8s.io/yaml v1.4.0
+)
+
+require (
+	github.com/miekg/dns v1.1.55 // indirect
+	golang.org/x/mod v0.17.0 // indirect
+	golang.org/x/net v0.34.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	golang.org/x/tools v0.21.1-0.20240508182429-e35e4ccd0d2d // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241202173237-19429a94021a // indirect
+	google.golang.org/protobuf v1.35.2 // indirect
 )
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Health history is bounded both ways: an hour of checks at the default
+// 5 second interval, and nothing older than an hour regardless of interval.
+const (
+	maxHealthHistory    = 720
+	healthHistoryMaxAge = time.Hour
+)
+
+// healthPreviewSize is how many recent outcomes the health summary includes
+const healthPreviewSize = 10
+
+// healthCheckTimeout bounds a single health probe
+const healthCheckTimeout = 5 * time.Second
+
+// maxHealthBody caps how much of a health response body is parsed
+const maxHealthBody = 64 * 1024
+
+var defaultHealthyValues = []string{"ok", "healthy", "up", "pass"}
+
+// probeResult is the outcome of a single health probe
+type probeResult struct {
+	Status string // Healthy, Degraded (...)
+	Code   int    // Protocol status code
+	Detail string // Status value reported by the app itself, if parsed
+}
+
+// probeHTTP checks an HTTP health endpoint. A non-200 response is Degraded;
+// a 200 is Healthy unless HealthJSONField says otherwise.
+func probeHTTP(cfg AppConfig) (probeResult, error) {
+	client, err := healthClient(cfg)
+	if err != nil {
+		return probeResult{}, err
+	}
+	resp, err := client.Get(cfg.HealthURL)
+	if err != nil {
+		return probeResult{}, err
+	}
+	defer resp.Body.Close()
+
+	result := probeResult{Code: resp.StatusCode}
+	if resp.StatusCode != http.StatusOK {
+		result.Status = fmt.Sprintf("Degraded (%d)", resp.StatusCode)
+		return result, nil
+	}
+	if cfg.HealthJSONField == "" {
+		result.Status = "Healthy"
+		return result, nil
+	}
+
+	value, err := healthBodyField(io.LimitReader(resp.Body, maxHealthBody), cfg.HealthJSONField)
+	if err != nil {
+		return result, err
+	}
+	result.Detail = value
+	healthy := cfg.HealthyValues
+	if len(healthy) == 0 {
+		healthy = defaultHealthyValues
+	}
+	for _, v := range healthy {
+		if strings.EqualFold(v, value) {
+			result.Status = "Healthy"
+			return result, nil
+		}
+	}
+	result.Status = fmt.Sprintf("Degraded (%s)", value)
+	return result, nil
+}
+
+// healthBodyField decodes a JSON health body and returns the value at the
+// dot-separated path, formatted as a string
+func healthBodyField(body io.Reader, path string) (string, error) {
+	var v any
+	if err := json.NewDecoder(body).Decode(&v); err != nil {
+		return "", fmt.Errorf("invalid health response body: %w", err)
+	}
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("health response has no field %q", path)
+		}
+		if v, ok = obj[key]; !ok {
+			return "", fmt.Errorf("health response has no field %q", path)
+		}
+	}
+	if str, ok := v.(string); ok {
+		return str, nil
+	}
+	return fmt.Sprint(v), nil
+}
+
+// probeHealth runs a single health probe against cfg's HealthURL over HTTP or
+// gRPC as its scheme says
+func probeHealth(cfg AppConfig) (probeResult, error) {
+	if isGRPCHealthURL(cfg.HealthURL) {
+		return probeGRPC(cfg)
+	}
+	return probeHTTP(cfg)
+}
+
+// passesHealth reports whether a health status means the app is serving,
+// including apps that are healthy but slow to answer
+func passesHealth(status string) bool {
+	return status == "Healthy" || strings.HasPrefix(status, "Slow")
+}
+
+// HealthResult is a single health check outcome kept for flap analysis
+type HealthResult struct {
+	Time      time.Time `json:"time"`
+	Status    string    `json:"status"`
+	LatencyMS float64   `json:"latency_ms"`
+	Marker    string    `json:"marker,omitempty"` // Set on entries marking an event rather than a check
+}
+
+// markerAlbertStarted marks where albert itself (re)started in each app's
+// history, so gaps from albert restarts aren't mistaken for app trouble
+const markerAlbertStarted = "albert_started"
+
+// recordHealth appends the current HealthStatus to the app's history and
+// drops entries past the count or age limit. Must be called with m.mu held.
+func (app *AppState) recordHealth(latency time.Duration) {
+	hub.Publish(EventHealth, HealthEvent{
+		App:       app.Config.Name,
+		Status:    app.HealthStatus,
+		Code:      app.HealthCode,
+		LatencyMS: latency.Milliseconds(),
+	})
+	app.HealthHistory = append(app.HealthHistory, HealthResult{
+		Time:      app.HealthLastCheck,
+		Status:    app.HealthStatus,
+		LatencyMS: float64(latency) / float64(time.Millisecond),
+	})
+
+	start := 0
+	if over := len(app.HealthHistory) - maxHealthHistory; over > 0 {
+		start = over
+	}
+	cutoff := app.HealthLastCheck.Add(-healthHistoryMaxAge)
+	for start < len(app.HealthHistory) && app.HealthHistory[start].Time.Before(cutoff) {
+		start++
+	}
+	if start > 0 {
+		app.HealthHistory = append([]HealthResult(nil), app.HealthHistory[start:]...)
+	}
+}
+
+// getHealthHistoryHandler returns the recorded health check outcomes for an app
+func getHealthHistoryHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	mgr.mu.RLock()
+	app, ok := mgr.apps[appName]
+	var history []HealthResult
+	if ok {
+		history = append([]HealthResult{}, app.HealthHistory...)
+	}
+	mgr.mu.RUnlock()
+
+	if !ok {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		log.Printf("Error encoding health history for %s: %v", appName, err)
+	}
+}
+
+// HealthSummary is the compact health view of one app
+type HealthSummary struct {
+	Status      string    `json:"status"`
+	Code        int       `json:"code"`
+	LatencyMS   int64     `json:"latency_ms"`
+	LastChecked time.Time `json:"last_checked"`
+	Recent      []bool    `json:"recent"` // Up/down for the latest checks, oldest first
+}
+
+// recentHealth returns whether each of the app's last healthPreviewSize
+// checks passed, oldest first
+func (app *AppState) recentHealth() []bool {
+	recent := make([]bool, 0, healthPreviewSize)
+	for i := len(app.HealthHistory) - 1; i >= 0 && len(recent) < healthPreviewSize; i-- {
+		if h := app.HealthHistory[i]; h.Marker == "" {
+			recent = append(recent, passesHealth(h.Status))
+		}
+	}
+	slices.Reverse(recent)
+	return recent
+}
+
+// getHealthSummaryHandler returns a map of app name to its latest health
+func getHealthSummaryHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	mgr.mu.RLock()
+	summary := make(map[string]HealthSummary, len(mgr.apps))
+	for name, app := range mgr.apps {
+		summary[name] = HealthSummary{
+			Status:      app.HealthStatus,
+			Code:        app.HealthCode,
+			LatencyMS:   app.HealthLatencyMS,
+			LastChecked: app.HealthLastCheck,
+			Recent:      app.recentHealth(),
+		}
+	}
+	mgr.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		log.Printf("Error encoding health summary: %v", err)
+	}
+}
+
+// Summary is an overview of albert as a whole
+type Summary struct {
+	Apps          int `json:"apps"`
+	Running       int `json:"running"`
+	Unhealthy     int `json:"unhealthy"`       // Running apps whose last health check failed
+	SSEClients    int `json:"sse_clients"`     // Connected /events clients
+	SSEMaxClients int `json:"sse_max_clients"` // Cap on /events clients, 0 if unlimited
+}
+
+// getSummaryHandler returns how many apps are running and healthy, and how
+// many /events clients are connected against the cap
+func getSummaryHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	var summary Summary
+	mgr.mu.RLock()
+	for _, app := range mgr.apps {
+		summary.Apps++
+		if app.Running {
+			summary.Running++
+			if isUnhealthy(app.HealthStatus) {
+				summary.Unhealthy++
+			}
+		}
+	}
+	mgr.mu.RUnlock()
+	summary.SSEClients, summary.SSEMaxClients = hub.Stats()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		log.Printf("Error encoding summary: %v", err)
+	}
+}
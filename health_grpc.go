@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// isGRPCHealthURL reports whether a HealthURL uses the gRPC health protocol
+func isGRPCHealthURL(healthURL string) bool {
+	return strings.HasPrefix(healthURL, "grpc://")
+}
+
+// probeGRPC calls the standard grpc.health.v1 Check RPC. The URL has the
+// form grpc://host:port[/service]; without a service the server's overall
+// health is checked. The result code is the numeric serving status.
+func probeGRPC(cfg AppConfig) (probeResult, error) {
+	u, err := url.Parse(cfg.HealthURL)
+	if err != nil {
+		return probeResult{}, err
+	}
+
+	conn, err := grpc.NewClient(u.Host, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return probeResult{}, err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: strings.TrimPrefix(u.Path, "/"),
+	})
+	if err != nil {
+		return probeResult{}, err
+	}
+
+	result := probeResult{Code: int(resp.Status), Detail: resp.Status.String()}
+	if resp.Status == healthpb.HealthCheckResponse_SERVING {
+		result.Status = "Healthy"
+	} else {
+		result.Status = fmt.Sprintf("Degraded (%s)", resp.Status)
+	}
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSummaryReportsEventClients(t *testing.T) {
+	m := newTestManager(t, AppConfig{Name: "web", Path: "sleep", Args: []string{"60"}}, AppConfig{Name: "db", Path: "sleep"})
+	if err := m.StartApp("web"); err != nil {
+		t.Fatal(err)
+	}
+	c, ok := hub.subscribe(nil)
+	if !ok {
+		t.Fatal("no room for an event client")
+	}
+	defer hub.unsubscribe(c)
+	clients, maxClients := hub.Stats()
+	if clients == 0 {
+		t.Fatal("subscribed client not counted")
+	}
+
+	w := httptest.NewRecorder()
+	getSummaryHandler(m, w, httptest.NewRequest("GET", "/api/summary", nil))
+	var got Summary
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := Summary{Apps: 2, Running: 1, SSEClients: clients, SSEMaxClients: maxClients}
+	if got != want {
+		t.Errorf("summary = %+v, want %+v", got, want)
+	}
+}
+
+func TestHealthClientCachedUntilReload(t *testing.T) {
+	cfg := AppConfig{Name: "web", Path: "sleep", HealthURL: "https://localhost:8443/health", HealthInsecureSkipVerify: true}
+	m := newTestManager(t, cfg)
+	defer healthClients.Forget("web")
+
+	first, err := healthClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := healthClient(cfg); again != first {
+		t.Error("health client rebuilt for an unchanged config")
+	}
+
+	cfg.HealthURL = "https://localhost:9443/health"
+	m.reloadMu.Lock()
+	m.applyConfigsLocked([]AppConfig{cfg}, false)
+	m.reloadMu.Unlock()
+	if reloaded, _ := healthClient(cfg); reloaded == first {
+		t.Error("health client kept across a reload that changed the app")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// healthTLSConfig builds the TLS settings for an app's HTTPS health checks,
+// or nil when the defaults apply
+func healthTLSConfig(cfg AppConfig) (*tls.Config, error) {
+	if cfg.HealthCAFile == "" && !cfg.HealthInsecureSkipVerify {
+		return nil, nil
+	}
+	tlsCfg := &tls.Config{InsecureSkipVerify: cfg.HealthInsecureSkipVerify}
+	if cfg.HealthCAFile != "" {
+		pem, err := os.ReadFile(cfg.HealthCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read health CA bundle for %s: %w", cfg.Name, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in health CA bundle %s for %s", cfg.HealthCAFile, cfg.Name)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	return tlsCfg, nil
+}
+
+// defaultHealthClient does the health checks of apps without custom TLS
+var defaultHealthClient = &http.Client{Timeout: healthCheckTimeout}
+
+// healthClientCache keeps the HTTP client of each app with custom health
+// TLS, so its CA bundle is read and its transport built once rather than on
+// every probe. A reload that changes an app drops its client.
+type healthClientCache struct {
+	mu      sync.Mutex
+	clients map[string]cachedHealthClient
+}
+
+// cachedHealthClient is an app's health client and the settings it was
+// built from
+type cachedHealthClient struct {
+	caFile   string
+	insecure bool
+	client   *http.Client
+}
+
+// healthClients holds the apps' custom TLS health clients
+var healthClients = &healthClientCache{clients: make(map[string]cachedHealthClient)}
+
+// healthClient returns the HTTP client for an app's health checks. Apps with
+// custom TLS get a cached transport of their own.
+func healthClient(cfg AppConfig) (*http.Client, error) {
+	if cfg.HealthCAFile == "" && !cfg.HealthInsecureSkipVerify {
+		return defaultHealthClient, nil
+	}
+	c := healthClients
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.clients[cfg.Name]
+	if ok && cached.caFile == cfg.HealthCAFile && cached.insecure == cfg.HealthInsecureSkipVerify {
+		return cached.client, nil
+	}
+	tlsCfg, err := healthTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	client := &http.Client{Timeout: healthCheckTimeout, Transport: transport}
+	if ok {
+		cached.client.CloseIdleConnections()
+	}
+	c.clients[cfg.Name] = cachedHealthClient{caFile: cfg.HealthCAFile, insecure: cfg.HealthInsecureSkipVerify, client: client}
+	return client, nil
+}
+
+// Forget drops an app's health client, so the next probe builds a new one
+// from its current settings and CA bundle
+func (c *healthClientCache) Forget(appName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.clients[appName]; ok {
+		cached.client.CloseIdleConnections()
+		delete(c.clients, appName)
+	}
+}
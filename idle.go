@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// lazyStartTimeout bounds how long a proxied request waits for an idle app to start
+const lazyStartTimeout = 30 * time.Second
+
+// stopIdleApps stops running apps with an IdleTimeout that haven't seen a
+// proxied request within it
+func (m *Manager) stopIdleApps() {
+	now := time.Now()
+	idle := m.selectApps(func(app *AppState) bool {
+		timeout := time.Duration(app.Config.IdleTimeout)
+		return app.Running && timeout > 0 && now.Sub(app.LastRequest) > timeout
+	})
+	for _, name := range idle {
+		if err := m.StopApp(name); err != nil {
+			appLogf(m.appConfig(name), "Failed to stop idle app %s: %v", name, err)
+			continue
+		}
+		appLogf(m.appConfig(name), "Stopped idle app %s", name)
+	}
+}
+
+// appConfig returns a copy of the named app's config
+func (m *Manager) appConfig(appName string) AppConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if app, ok := m.apps[appName]; ok {
+		return app.Config
+	}
+	return AppConfig{Name: appName}
+}
+
+// ensureStarted starts an app with an IdleTimeout on demand and waits for it
+// to become ready. It returns an error if the app isn't running and can't be
+// started lazily.
+func (m *Manager) ensureStarted(ctx context.Context, appName string) error {
+	m.mu.RLock()
+	app, ok := m.apps[appName]
+	running := ok && app.Running
+	lazy := ok && app.Config.IdleTimeout > 0
+	m.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("app %s not found", appName)
+	}
+	if running {
+		return nil
+	}
+	if !lazy {
+		return fmt.Errorf("app %s is not running", appName)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lazyStartTimeout)
+	defer cancel()
+	appLogf(m.appConfig(appName), "Starting idle app %s on demand", appName)
+	if err := m.StartApp(appName); err != nil {
+		switch {
+		case errors.Is(err, errAlreadyStarting):
+			// Another request got there first; ride along with its start
+			if err := m.waitStarted(ctx, appName); err != nil {
+				return err
+			}
+		case !errors.Is(err, errAlreadyRunning):
+			return err
+		}
+	}
+	return m.waitReady(ctx, appName)
+}
+
+// waitStarted waits for a start already in progress to launch the app's
+// process, or for ctx to be done
+func (m *Manager) waitStarted(ctx context.Context, appName string) error {
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+	for {
+		m.mu.RLock()
+		app, ok := m.apps[appName]
+		starting, running := ok && app.Starting, ok && app.Running
+		m.mu.RUnlock()
+		if !starting {
+			if !running {
+				return fmt.Errorf("app %s failed to start", appName)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("app %s did not start: %w", appName, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
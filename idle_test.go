@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentRequestsStartIdleAppOnce(t *testing.T) {
+	// The dependency keeps web Starting for a while, so both requests find
+	// it stopped or starting
+	m := newTestManager(t,
+		AppConfig{Name: "db", Path: "sleep", Args: []string{"60"}},
+		AppConfig{Name: "web", Path: "sleep", Args: []string{"60"}, DependsOn: []string{"db"}, IdleTimeout: Duration(time.Hour)},
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = m.ensureStarted(ctx, "web")
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("request %d: %v", i, err)
+		}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if app := m.apps["web"]; !app.Running || app.RunCount != 1 {
+		t.Errorf("web running %v with run count %d, want running once", app.Running, app.RunCount)
+	}
+}
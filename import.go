@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// importers convert another process manager's definitions to AppConfigs.
+// Each takes a single file; directories are read file by file.
+var importers = map[string]struct {
+	exts  []string // File extensions read from a directory
+	parse func(path string, data []byte) ([]AppConfig, error)
+}{
+	"supervisord": {exts: []string{".conf", ".ini"}, parse: parseSupervisord},
+	"pm2":         {exts: []string{".json"}, parse: parsePM2},
+	"systemd":     {exts: []string{".service"}, parse: parseSystemdUnit},
+}
+
+// runImport implements "albert import --from <manager> <path>": it converts
+// a file or directory of another process manager's definitions to an albert
+// config and writes it to --out or stdout. Returns the exit code.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	from := fs.String("from", "", "format to import: supervisord, pm2 (JSON process files) or systemd")
+	out := fs.String("out", "", "write the config to this file, in the format its extension implies (default stdout)")
+	format := fs.String("format", "json", "format for stdout: json, yaml or toml")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	imp, ok := importers[*from]
+	if !ok || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: albert import --from supervisord|pm2|systemd [--out file] <file or directory>")
+		return 2
+	}
+
+	apps, err := importPath(fs.Arg(0), imp.exts, imp.parse)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
+	}
+	if err := validateConfigs(apps); err != nil {
+		fmt.Fprintf(os.Stderr, "Imported config is invalid: %v\n", err)
+		return 1
+	}
+
+	fc := FileConfig{Apps: apps}
+	if *out != "" {
+		if err := saveConfigFile(*out, fc); err != nil {
+			fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Imported %d apps to %s\n", len(apps), *out)
+		return 0
+	}
+	data, err := encodeConfig("."+*format, fc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
+	}
+	os.Stdout.Write(data)
+	return 0
+}
+
+// importPath parses a single file, or every file with one of exts in a
+// directory in name order
+func importPath(path string, exts []string, parse func(string, []byte) ([]AppConfig, error)) ([]AppConfig, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if st.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, e := range entries {
+			for _, ext := range exts {
+				if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ext) {
+					files = append(files, filepath.Join(path, e.Name()))
+				}
+			}
+		}
+		sort.Strings(files)
+	}
+
+	var apps []AppConfig
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parse(file, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		apps = append(apps, parsed...)
+	}
+	return apps, nil
+}
+
+// iniSection is one [section] of an INI-style file, keeping repeated keys
+type iniSection struct {
+	Name string
+	Keys [][2]string
+}
+
+// get returns the last value of key in the section
+func (s iniSection) get(key string) string {
+	var v string
+	for _, kv := range s.Keys {
+		if kv[0] == key {
+			v = kv[1]
+		}
+	}
+	return v
+}
+
+// parseINI reads the sections of a supervisord or systemd style file. Lines
+// starting with # or ; are comments, a trailing backslash joins the next
+// line, and for supervisord indented lines continue the previous value.
+func parseINI(data []byte) []iniSection {
+	var sections []iniSection
+	var pending string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		joined := pending != ""
+		if joined {
+			line = pending + " " + line
+			pending = ""
+		}
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			pending = strings.TrimSuffix(line, "\\")
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			sections = append(sections, iniSection{Name: strings.TrimSpace(line[1 : len(line)-1])})
+			continue
+		}
+		if len(sections) == 0 {
+			continue
+		}
+		cur := &sections[len(sections)-1]
+		k, v, ok := strings.Cut(line, "=")
+		if !joined && (!ok || raw[0] == ' ' || raw[0] == '\t') {
+			if n := len(cur.Keys); n > 0 {
+				cur.Keys[n-1][1] += " " + line // Continuation line
+			}
+			continue
+		}
+		cur.Keys = append(cur.Keys, [2]string{strings.TrimSpace(k), strings.TrimSpace(v)})
+	}
+	return sections
+}
+
+// splitCommand splits a command line into words the way a shell would for
+// simple cases: whitespace separates words, quotes group them and a
+// backslash escapes the next character
+func splitCommand(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// supervisordVar matches %(name)s expansions in supervisord values
+var supervisordVar = regexp.MustCompile(`%\(([A-Za-z0-9_]+)\)s`)
+
+// parseSupervisord converts the [program:x] sections of a supervisord
+// config. %(ENV_X)s becomes ${X}, which albert expands the same way.
+func parseSupervisord(path string, data []byte) ([]AppConfig, error) {
+	var apps []AppConfig
+	for _, sec := range parseINI(data) {
+		name, ok := strings.CutPrefix(sec.Name, "program:")
+		if !ok {
+			continue
+		}
+		expand := func(v string) string {
+			v, _, _ = strings.Cut(v, " ;") // Inline comment
+			return supervisordVar.ReplaceAllStringFunc(v, func(ref string) string {
+				key := supervisordVar.FindStringSubmatch(ref)[1]
+				switch {
+				case key == "program_name":
+					return name
+				case key == "here":
+					if dir, err := filepath.Abs(filepath.Dir(path)); err == nil {
+						return dir
+					}
+					return filepath.Dir(path)
+				case strings.HasPrefix(key, "ENV_"):
+					return "${" + strings.TrimPrefix(key, "ENV_") + "}"
+				}
+				return ref
+			})
+		}
+
+		words, err := splitCommand(expand(sec.get("command")))
+		if err != nil {
+			return nil, fmt.Errorf("program %s: %w", name, err)
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("program %s has no command", name)
+		}
+		cfg := AppConfig{Name: name, Path: words[0], Args: words[1:], WorkDir: expand(sec.get("directory")), User: sec.get("user")}
+		if env := sec.get("environment"); env != "" {
+			if cfg.Env, err = parseSupervisordEnv(expand(env)); err != nil {
+				return nil, fmt.Errorf("program %s: %w", name, err)
+			}
+		}
+		apps = append(apps, cfg)
+	}
+	return apps, nil
+}
+
+// parseSupervisordEnv parses a supervisord environment value:
+// KEY="value",KEY2=value2
+func parseSupervisordEnv(s string) (map[string]string, error) {
+	env := map[string]string{}
+	var pairs []string
+	var cur strings.Builder
+	inQuote := false
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\'':
+			inQuote = !inQuote
+			cur.WriteRune(r)
+		case r == ',' && !inQuote:
+			pairs = append(pairs, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	pairs = append(pairs, cur.String())
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid environment entry %q", pair)
+		}
+		env[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"'`)
+	}
+	return env, nil
+}
+
+// pm2App is the subset of a pm2 process file entry albert understands
+type pm2App struct {
+	Name        string            `json:"name"`
+	Script      string            `json:"script"`
+	Args        json.RawMessage   `json:"args"` // A string or a list
+	Cwd         string            `json:"cwd"`
+	Interpreter string            `json:"interpreter"`
+	Env         map[string]string `json:"env"`
+}
+
+// parsePM2 converts a pm2 JSON process file, either {"apps": [...]} or a
+// bare list. JavaScript ecosystem files aren't supported; export them with
+// "pm2 save" or convert them to JSON first.
+func parsePM2(path string, data []byte) ([]AppConfig, error) {
+	var file struct {
+		Apps []pm2App `json:"apps"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		if err := json.Unmarshal(data, &file.Apps); err != nil {
+			return nil, fmt.Errorf("not a pm2 JSON process file: %w", err)
+		}
+	}
+
+	apps := make([]AppConfig, 0, len(file.Apps))
+	for _, p := range file.Apps {
+		if p.Script == "" {
+			return nil, fmt.Errorf("pm2 app %q has no script", p.Name)
+		}
+		name := p.Name
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(p.Script), filepath.Ext(p.Script))
+		}
+
+		var args []string
+		if len(p.Args) > 0 {
+			var s string
+			if err := json.Unmarshal(p.Args, &s); err == nil {
+				var err error
+				if args, err = splitCommand(s); err != nil {
+					return nil, fmt.Errorf("pm2 app %s: %w", name, err)
+				}
+			} else if err := json.Unmarshal(p.Args, &args); err != nil {
+				return nil, fmt.Errorf("pm2 app %s: args must be a string or a list", name)
+			}
+		}
+
+		// pm2 runs .js files with node unless told otherwise
+		interpreter := p.Interpreter
+		if interpreter == "" && strings.HasSuffix(p.Script, ".js") {
+			interpreter = "node"
+		}
+		cfg := AppConfig{Name: name, Path: p.Script, Args: args, WorkDir: p.Cwd, Env: p.Env}
+		if interpreter != "" && interpreter != "none" {
+			cfg.Path = interpreter
+			cfg.Args = append([]string{p.Script}, args...)
+		}
+		apps = append(apps, cfg)
+	}
+	return apps, nil
+}
+
+// parseSystemdUnit converts a systemd .service unit, named after the file
+func parseSystemdUnit(path string, data []byte) ([]AppConfig, error) {
+	name := strings.TrimSuffix(filepath.Base(path), ".service")
+	cfg := AppConfig{Name: name}
+	for _, sec := range parseINI(data) {
+		if sec.Name != "Service" {
+			continue
+		}
+		for _, kv := range sec.Keys {
+			switch kv[0] {
+			case "ExecStart":
+				// Drop the special prefixes (-, @, :, +, !) that change how
+				// systemd runs the command
+				words, err := splitCommand(strings.TrimLeft(kv[1], "-@:+!"))
+				if err != nil {
+					return nil, fmt.Errorf("ExecStart: %w", err)
+				}
+				if len(words) == 0 {
+					continue // An empty ExecStart resets the list
+				}
+				cfg.Path, cfg.Args = words[0], words[1:]
+			case "WorkingDirectory":
+				cfg.WorkDir = strings.TrimPrefix(kv[1], "-")
+			case "User":
+				cfg.User = kv[1]
+			case "Group":
+				cfg.Group = kv[1]
+			case "EnvironmentFile":
+				cfg.EnvFile = strings.TrimPrefix(kv[1], "-")
+			case "Environment":
+				words, err := splitCommand(kv[1])
+				if err != nil {
+					return nil, fmt.Errorf("Environment: %w", err)
+				}
+				for _, w := range words {
+					k, v, ok := strings.Cut(w, "=")
+					if !ok {
+						continue
+					}
+					if cfg.Env == nil {
+						cfg.Env = map[string]string{}
+					}
+					cfg.Env[k] = v
+				}
+			}
+		}
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("unit has no ExecStart")
+	}
+	return []AppConfig{cfg}, nil
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Job statuses
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobTimedOut  = "timed_out"
+	JobCancelled = "cancelled"
+)
+
+const (
+	defaultJobTimeout = 10 * time.Minute
+	jobOutputLimit    = 64 * 1024 // Bytes of output kept per job, the most recent
+	jobHistoryLimit   = 50        // Finished jobs kept for GET /api/jobs
+)
+
+var errJobNotFound = errors.New("job not found")
+
+// JobConfig is a one-shot command: a predefined task, or an ad-hoc command
+// posted to /api/jobs. It runs to completion instead of being kept up.
+type JobConfig struct {
+	Name    string            `json:"name"`
+	Path    string            `json:"path"`
+	Args    []string          `json:"args"`
+	Shell   bool              `json:"shell"` // Run Path as an sh -c script, as for apps
+	WorkDir string            `json:"workdir"`
+	Env     map[string]string `json:"env"`
+	Timeout Duration          `json:"timeout"` // Killed after this long; default 10m
+}
+
+// Job is one run of a JobConfig
+type Job struct {
+	ID       int        `json:"id"`
+	Task     string     `json:"task,omitempty"` // Predefined task run; empty for ad-hoc commands
+	Command  []string   `json:"command"`
+	Status   string     `json:"status"`
+	ExitCode *int       `json:"exit_code,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Output   string     `json:"output,omitempty"` // Only in single-job responses
+
+	output    byteBuffer
+	cancel    context.CancelFunc
+	cancelled bool
+	done      chan struct{} // Closed once the job has finished
+}
+
+// jobWriter appends a job's output under the jobs lock, redacting the
+// secrets it was started with
+type jobWriter struct {
+	m      *Manager
+	job    *Job
+	redact *strings.Replacer
+}
+
+func (w jobWriter) Write(p []byte) (int, error) {
+	chunk := p
+	if w.redact != nil { // A secret split across writes slips through
+		chunk = []byte(w.redact.Replace(string(p)))
+	}
+	w.m.jobsMu.Lock()
+	defer w.m.jobsMu.Unlock()
+	w.job.output.Write(chunk)
+	return len(p), nil
+}
+
+// SetJobTasks replaces the predefined one-shot tasks
+func (m *Manager) SetJobTasks(tasks []JobConfig) {
+	m.jobsMu.Lock()
+	defer m.jobsMu.Unlock()
+	m.jobTasks = make(map[string]JobConfig, len(tasks))
+	for _, t := range tasks {
+		m.jobTasks[t.Name] = t
+	}
+}
+
+// jobTask returns a predefined task by name
+func (m *Manager) jobTask(name string) (JobConfig, bool) {
+	m.jobsMu.Lock()
+	defer m.jobsMu.Unlock()
+	task, ok := m.jobTasks[name]
+	return task, ok
+}
+
+// RunJob starts a one-shot job in the background and returns a snapshot of
+// it. task names the predefined task being run, if any.
+func (m *Manager) RunJob(jc JobConfig, task string) (Job, error) {
+	if jc.Path == "" {
+		return Job{}, fmt.Errorf("job has no path")
+	}
+	name := jc.Name
+	if name == "" {
+		name = "job"
+	}
+	cfg, redact, err := resolveSecrets(AppConfig{Name: name, Path: jc.Path, Args: jc.Args, Shell: jc.Shell, WorkDir: jc.WorkDir, Env: jc.Env})
+	if err != nil {
+		return Job{}, err
+	}
+	env, err := appEnv(cfg)
+	if err != nil {
+		return Job{}, err
+	}
+	timeout := time.Duration(jc.Timeout)
+	if timeout <= 0 {
+		timeout = defaultJobTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	args := appCommand(cfg).Args
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = env
+	cmd.Dir = cfg.WorkDir
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return killApp(cmd.Process) }
+	cmd.WaitDelay = time.Second // Don't wait on children still holding the output open
+
+	m.jobsMu.Lock()
+	m.nextJobID++
+	job := &Job{
+		ID:      m.nextJobID,
+		Task:    task,
+		Command: append([]string{jc.Path}, jc.Args...),
+		Status:  JobRunning,
+		Started: time.Now(),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	job.output.SetLimit(jobOutputLimit)
+	m.jobsMu.Unlock()
+	cmd.Stdout = jobWriter{m, job, redact}
+	cmd.Stderr = cmd.Stdout
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return Job{}, fmt.Errorf("failed to start job: %w", err)
+	}
+	m.jobsMu.Lock()
+	m.jobs = append(m.jobs, job)
+	m.pruneJobs()
+	snapshot := job.snapshot(false)
+	m.jobsMu.Unlock()
+	log.Printf("Started job %d: %s", job.ID, name)
+
+	go func() {
+		err := cmd.Wait()
+		cancel()
+		finished := time.Now()
+
+		m.jobsMu.Lock()
+		defer m.jobsMu.Unlock()
+		job.Finished = &finished
+		if code := cmd.ProcessState.ExitCode(); code >= 0 {
+			job.ExitCode = &code
+		}
+		switch {
+		case job.cancelled:
+			job.Status = JobCancelled
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			job.Status = JobTimedOut
+			job.Error = fmt.Sprintf("killed after %s", timeout)
+		case err != nil:
+			job.Status = JobFailed
+			job.Error = err.Error()
+		default:
+			job.Status = JobSucceeded
+		}
+		close(job.done)
+		log.Printf("Job %d (%s) %s after %s", job.ID, name, job.Status, finished.Sub(job.Started).Round(time.Millisecond))
+	}()
+	return snapshot, nil
+}
+
+// pruneJobs drops the oldest finished jobs past jobHistoryLimit. Must be
+// called with m.jobsMu held.
+func (m *Manager) pruneJobs() {
+	finished := 0
+	for _, job := range m.jobs {
+		if job.Status != JobRunning {
+			finished++
+		}
+	}
+	kept := m.jobs[:0]
+	for _, job := range m.jobs {
+		if job.Status != JobRunning && finished > jobHistoryLimit {
+			finished--
+			continue
+		}
+		kept = append(kept, job)
+	}
+	m.jobs = kept
+}
+
+// snapshot copies a job for JSON, with its output if withOutput is set.
+// Must be called with m.jobsMu held.
+func (job *Job) snapshot(withOutput bool) Job {
+	s := Job{
+		ID:       job.ID,
+		Task:     job.Task,
+		Command:  job.Command,
+		Status:   job.Status,
+		ExitCode: job.ExitCode,
+		Error:    job.Error,
+		Started:  job.Started,
+		Finished: job.Finished,
+	}
+	if withOutput {
+		s.Output = string(job.output.Bytes())
+	}
+	return s
+}
+
+// findJob returns a job by ID. Must be called with m.jobsMu held.
+func (m *Manager) findJob(id int) (*Job, error) {
+	for _, job := range m.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("job %d: %w", id, errJobNotFound)
+}
+
+// Jobs lists recent jobs, newest first, without their output
+func (m *Manager) Jobs() []Job {
+	m.jobsMu.Lock()
+	defer m.jobsMu.Unlock()
+	jobs := make([]Job, len(m.jobs))
+	for i, job := range m.jobs {
+		jobs[len(m.jobs)-1-i] = job.snapshot(false)
+	}
+	return jobs
+}
+
+// JobResult returns a job with its output. With wait it first waits for the
+// job to finish or ctx to be done.
+func (m *Manager) JobResult(ctx context.Context, id int, wait bool) (Job, error) {
+	m.jobsMu.Lock()
+	job, err := m.findJob(id)
+	m.jobsMu.Unlock()
+	if err != nil {
+		return Job{}, err
+	}
+	if wait {
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+		}
+	}
+	m.jobsMu.Lock()
+	defer m.jobsMu.Unlock()
+	return job.snapshot(true), nil
+}
+
+// CancelJob kills a running job and its children
+func (m *Manager) CancelJob(id int) error {
+	m.jobsMu.Lock()
+	defer m.jobsMu.Unlock()
+	job, err := m.findJob(id)
+	if err != nil {
+		return err
+	}
+	if job.Status != JobRunning {
+		return fmt.Errorf("job %d is not running (%s)", id, job.Status)
+	}
+	job.cancelled = true
+	job.cancel()
+	return nil
+}
+
+// runJobHandler starts a job: {"task": "name"} runs a predefined task, and
+// otherwise the body is an ad-hoc JobConfig, refused with 403 unless albert
+// runs with -allow-adhoc-jobs. Responds 202 with the job, or with
+// ?wait=true, 200 with its result once it has finished.
+func runJobHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Task string `json:"task"`
+		JobConfig
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid job: %v", err), http.StatusBadRequest)
+		return
+	}
+	jc := req.JobConfig
+	if req.Task != "" {
+		task, ok := mgr.jobTask(req.Task)
+		if !ok {
+			http.Error(w, fmt.Sprintf("task %s not found", req.Task), http.StatusNotFound)
+			return
+		}
+		jc = task
+	} else if !mgr.adhocJobs {
+		http.Error(w, "ad-hoc jobs are disabled; run a predefined task, or start albert with -allow-adhoc-jobs", http.StatusForbidden)
+		return
+	}
+
+	job, err := mgr.RunJob(jc, req.Task)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status := http.StatusAccepted
+	if r.URL.Query().Get("wait") == "true" {
+		if job, err = mgr.JobResult(r.Context(), job.ID, true); err == nil && job.Status != JobRunning {
+			status = http.StatusOK
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Printf("Error encoding job: %v", err)
+	}
+}
+
+// listJobsHandler lists recent jobs, newest first
+func listJobsHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(mgr.Jobs()); err != nil {
+		log.Printf("Error encoding jobs: %v", err)
+	}
+}
+
+// getJobHandler returns a job with its output. With ?wait=true it waits
+// for the job to finish first.
+func getJobHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid job id", http.StatusBadRequest)
+		return
+	}
+	job, err := mgr.JobResult(r.Context(), id, r.URL.Query().Get("wait") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Printf("Error encoding job %d: %v", id, err)
+	}
+}
+
+// cancelJobHandler kills a running job
+func cancelJobHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid job id", http.StatusBadRequest)
+		return
+	}
+	if err := mgr.CancelJob(id); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, errJobNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "{\"status\": \"success\", \"message\": \"cancelled job %d\"}", id)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdhocJobsNeedOptIn(t *testing.T) {
+	m := newTestManager(t)
+	m.SetJobTasks([]JobConfig{{Name: "greet", Path: "echo", Args: []string{"hi"}}})
+	post := func(body string) int {
+		w := httptest.NewRecorder()
+		runJobHandler(m, w, httptest.NewRequest("POST", "/api/jobs?wait=true", strings.NewReader(body)))
+		return w.Code
+	}
+
+	if code := post(`{"path": "echo", "args": ["hi"]}`); code != http.StatusForbidden {
+		t.Errorf("ad-hoc job without opting in: status %d, want %d", code, http.StatusForbidden)
+	}
+	if code := post(`{"task": "greet"}`); code != http.StatusOK {
+		t.Errorf("predefined task: status %d, want %d", code, http.StatusOK)
+	}
+	m.adhocJobs = true
+	if code := post(`{"path": "echo", "args": ["hi"]}`); code != http.StatusOK {
+		t.Errorf("ad-hoc job after opting in: status %d, want %d", code, http.StatusOK)
+	}
+}
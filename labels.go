@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// sortedLabelKeys returns the keys of labels, or any other map by name, in
+// a stable order
+func sortedLabelKeys[V any](labels map[string]V) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// logFields renders the app's labels as " key=value" pairs for log lines
+func (cfg AppConfig) logFields() string {
+	var b strings.Builder
+	for _, k := range sortedLabelKeys(cfg.Labels) {
+		fmt.Fprintf(&b, " %s=%q", k, cfg.Labels[k])
+	}
+	return b.String()
+}
+
+// appLogf logs like log.Printf with the app's labels appended as fields
+func appLogf(cfg AppConfig, format string, v ...any) {
+	log.Print(fmt.Sprintf(format, v...) + cfg.logFields())
+}
+
+// parseLabelSelector reads key=value pairs that an app's labels must all
+// match, e.g. from repeated ?label= query params
+func parseLabelSelector(pairs []string) (map[string]string, error) {
+	selector := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label selector %q, want key=value", pair)
+		}
+		selector[k] = v
+	}
+	return selector, nil
+}
+
+// matchesLabels reports whether the app has every label in selector
+func (cfg AppConfig) matchesLabels(selector map[string]string) bool {
+	for k, v := range selector {
+		if got, ok := cfg.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
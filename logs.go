@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxLogEntries bounds the log entries kept per app
+const maxLogEntries = 1000
+
+// maxPartialLine is the longest unterminated line held back before it is
+// emitted as-is
+const maxPartialLine = 64 * 1024
+
+// LogEntry is one line of app output. Lines that parse as a JSON object have
+// their level, message and timestamp extracted; other lines keep only Raw.
+// Time is when albert captured the line unless the app's own timestamp
+// could be read from it.
+type LogEntry struct {
+	Time   time.Time      `json:"time"`
+	Level  string         `json:"level,omitempty"`
+	Msg    string         `json:"msg"`
+	Fields map[string]any `json:"fields,omitempty"`
+	Raw    string         `json:"raw,omitempty"`
+}
+
+// lineSplitter turns arbitrary output chunks into complete lines
+type lineSplitter struct {
+	partial []byte
+}
+
+// Feed adds a chunk of output and returns any lines it completed
+func (ls *lineSplitter) Feed(chunk []byte) []string {
+	var lines []string
+	for {
+		i := bytes.IndexByte(chunk, '\n')
+		if i < 0 {
+			break
+		}
+		line := append(ls.partial, chunk[:i]...)
+		lines = append(lines, strings.TrimSuffix(string(line), "\r"))
+		ls.partial = ls.partial[:0]
+		chunk = chunk[i+1:]
+	}
+	ls.partial = append(ls.partial, chunk...)
+	if len(ls.partial) > maxPartialLine {
+		lines = append(lines, string(ls.partial))
+		ls.partial = ls.partial[:0]
+	}
+	return lines
+}
+
+var (
+	logLevelKeys = []string{"level", "lvl", "severity"}
+	logMsgKeys   = []string{"msg", "message"}
+	logTimeKeys  = []string{"ts", "time", "timestamp"}
+)
+
+// takeField removes and returns the first of keys present in fields
+func takeField(fields map[string]any, keys []string) (any, bool) {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok {
+			delete(fields, k)
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// parseLogTime interprets a JSON timestamp as RFC 3339 or Unix seconds
+func parseLogTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		return parsed, err == nil
+	case float64:
+		sec := int64(t)
+		return time.Unix(sec, int64((t-float64(sec))*1e9)), true
+	}
+	return time.Time{}, false
+}
+
+// parseJSONLogLine builds a LogEntry from a line, extracting level, msg and
+// timestamp when the line is a JSON object. captured is used when the line
+// carries no timestamp of its own.
+func parseJSONLogLine(line string, captured time.Time) LogEntry {
+	entry := LogEntry{Time: captured, Msg: line, Raw: line}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(line), &fields); err != nil || fields == nil {
+		return entry
+	}
+
+	entry.Raw = ""
+	entry.Msg = ""
+	if v, ok := takeField(fields, logLevelKeys); ok {
+		if s, ok := v.(string); ok {
+			entry.Level = strings.ToLower(s)
+		}
+	}
+	if v, ok := takeField(fields, logMsgKeys); ok {
+		if s, ok := v.(string); ok {
+			entry.Msg = s
+		}
+	}
+	if v, ok := takeField(fields, logTimeKeys); ok {
+		if t, ok := parseLogTime(v); ok {
+			entry.Time = t
+		}
+	}
+	if len(fields) > 0 {
+		entry.Fields = fields
+	}
+	return entry
+}
+
+// appendLogEntries stores structured entries, dropping the oldest past
+// maxLogEntries. Must be called with m.mu held.
+func (app *AppState) appendLogEntries(entries []LogEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	app.LogEntries = append(app.LogEntries, entries...)
+	if over := len(app.LogEntries) - maxLogEntries; over > 0 {
+		app.LogEntries = append([]LogEntry(nil), app.LogEntries[over:]...)
+	}
+}
+
+var logLevelRank = map[string]int{
+	"trace": 0, "debug": 1, "info": 2, "notice": 2,
+	"warn": 3, "warning": 3, "error": 4, "err": 4,
+	"fatal": 5, "panic": 5, "critical": 5,
+}
+
+// filterLogEntries keeps entries at or above minLevel. Entries without a
+// recognised level are dropped when filtering.
+func filterLogEntries(entries []LogEntry, minLevel string) []LogEntry {
+	min, ok := logLevelRank[strings.ToLower(minLevel)]
+	if minLevel == "" || !ok {
+		return entries
+	}
+	kept := []LogEntry{}
+	for _, e := range entries {
+		if rank, ok := logLevelRank[e.Level]; ok && rank >= min {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// timestampExtractor pulls an app's own timestamp out of its log lines
+type timestampExtractor struct {
+	re     *regexp.Regexp
+	layout string
+}
+
+// newTimestampExtractor compiles an app's TimestampPattern. The first
+// capture group (or the whole match) is parsed with TimestampFormat, which
+// defaults to RFC 3339.
+func newTimestampExtractor(cfg AppConfig) (*timestampExtractor, error) {
+	re, err := regexp.Compile(cfg.TimestampPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp pattern for %s: %w", cfg.Name, err)
+	}
+	layout := cfg.TimestampFormat
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	return &timestampExtractor{re: re, layout: layout}, nil
+}
+
+// Extract returns the timestamp found in line, if any parses
+func (te *timestampExtractor) Extract(line string) (time.Time, bool) {
+	match := te.re.FindStringSubmatch(line)
+	if match == nil {
+		return time.Time{}, false
+	}
+	value := match[0]
+	if len(match) > 1 {
+		value = match[1]
+	}
+	t, err := time.ParseInLocation(te.layout, value, time.Local)
+	return t, err == nil
+}
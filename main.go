@@ -2,18 +2,29 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"os"
 	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 	
 	"github.com/RoughCookiexx/gg_sse"
 	"github.com/RoughCookiexx/gg_twitch_types"
 	"github.com/RoughCookiexx/twitch_chat_subscriber"
+	"github.com/hashicorp/mdns"
+	"github.com/robfig/cron/v3"
 )
 
 // Define the AppConfig structure for applications to be managed
@@ -22,6 +33,174 @@ type AppConfig struct {
 	Path      string   `json:"path"`
 	Args      []string `json:"args"`
 	HealthURL string   `json:"health_url"`
+	Priority  int      `json:"priority"`  // Relative weight for shared resources; <= 0 counts as 1
+	Port      int      `json:"port"`      // Port the app serves HTTP on, used for proxying
+	Autostart bool     `json:"autostart"` // Start when albert starts, once prerequisites are reachable
+
+	// Apps start in ascending StartOrder on autostart and start-all; apps
+	// sharing a value start together
+	StartOrder int `json:"start_order"`
+
+	// Apps that must be running and ready before this one starts. Starting
+	// this app starts them first if needed.
+	DependsOn []string `json:"depends_on"`
+
+	Schedule []ScheduleEntry `json:"schedule"` // Cron-style start, stop and restart times
+
+	// Args and Env values of the form secret://name are resolved through the
+	// secrets backend at each start and redacted from captured output
+	Env         map[string]string `json:"env"`          // Set in the app's environment, overriding albert's own
+	EnvFile     string            `json:"env_file"`     // .env file read on each start; Env overrides it
+	RequiredEnv []string          `json:"required_env"` // Env vars that must be non-empty before starting
+	Labels      map[string]string `json:"labels"`       // Attached to this app's metrics and log lines
+
+	MinFreeDiskMB int    `json:"min_free_disk_mb"` // Refuse to start below this much free space
+	DiskPath      string `json:"disk_path"`        // Where to measure free space; defaults to the binary's directory
+
+	FIFOPath string `json:"fifo_path"` // Named pipe that mirrors the app's output while it runs
+
+	IdleTimeout Duration `json:"idle_timeout"` // Stop after this long without proxied requests; started again on demand
+
+	// For apps that always answer 200, read the real status from a JSON field
+	// in the health response (dot-separated path, e.g. "checks.db.status").
+	// Values in HealthyValues (default "ok", "healthy", "up", "pass") are
+	// Healthy; anything else is Degraded.
+	HealthJSONField string   `json:"health_json_field"`
+	HealthyValues   []string `json:"healthy_values"`
+
+	HealthSlowThreshold Duration `json:"health_slow_threshold"` // Healthy checks slower than this report "Slow"
+
+	// Separate readiness and liveness probes, each defaulting to HealthURL.
+	// A started app shows "Starting" until ReadyURL passes (probed every
+	// ReadyInterval, default 1s); dependency starts, rolling restarts and
+	// deploy cutovers wait for it. LiveURL gives the health status, checked
+	// at most every LiveInterval (default every health sweep), and
+	// LiveFailures failed checks in a row (default 0, never) restart the app.
+	// A run that isn't ready StartTimeout after it started is killed and
+	// counts as a failed start.
+	ReadyURL      string   `json:"ready_url"`
+	ReadyInterval Duration `json:"ready_interval"`
+	StartTimeout  Duration `json:"start_timeout"`
+	LiveURL       string   `json:"live_url"`
+	LiveInterval  Duration `json:"live_interval"`
+	LiveFailures  int      `json:"live_failures"`
+
+	// TLS for HTTPS health checks: a PEM bundle of CAs to trust instead of the
+	// system ones, or (discouraged) no certificate verification at all
+	HealthCAFile             string `json:"health_ca_file"`
+	HealthInsecureSkipVerify bool   `json:"health_insecure_skip_verify"`
+
+	// Sandboxing (Linux, needs root). With Chroot set, Path is resolved
+	// inside the new root. Namespaces may include mount, pid, net, uts, ipc.
+	Chroot     string   `json:"chroot"`
+	Namespaces []string `json:"namespaces"`
+
+	MaxOpenFiles uint64 `json:"max_open_files"` // RLIMIT_NOFILE for the app's process
+
+	ReadBufferSize int `json:"read_buffer_size"` // Bytes read from the app's output per syscall
+
+	// Which output to keep: "bytes" (default) keeps the most recent bytes,
+	// "lines" the last BufferLines lines (default 500) and "time" whatever was
+	// written within BufferWindow (default 10m). Every strategy also stays
+	// within the app's share of the output budget.
+	BufferStrategy string   `json:"buffer_strategy"`
+	BufferLines    int      `json:"buffer_lines"`
+	BufferWindow   Duration `json:"buffer_window"`
+
+	// Singleton apps hold an exclusive lock for as long as they run, so a
+	// leftover instance (e.g. from before albert restarted) blocks new starts
+	Singleton bool `json:"singleton"`
+
+	JSONLogs bool `json:"json_logs"` // Parse output lines as JSON log entries
+
+	// Read each line's timestamp from the line itself: TimestampPattern is a
+	// regexp whose first group (or whole match) is parsed with the Go layout
+	// TimestampFormat (default RFC 3339). Capture time is used if it fails.
+	TimestampPattern string `json:"timestamp_pattern"`
+	TimestampFormat  string `json:"timestamp_format"`
+
+	// Shell runs Path as an sh -c script instead of executing it directly, so
+	// pipelines, redirects and globs work; Args become $1, $2, ... The
+	// tradeoffs: quoting is up to the config, the pid albert tracks is the
+	// shell's (max_open_files and memory sampling see only the shell), and
+	// there is no binary to deploy.
+	Shell bool `json:"shell"`
+
+	// Announce at most one crash per CrashAlertInterval. Crashes in between
+	// are counted, and the next alert is critical if there were any.
+	CrashAlertInterval Duration `json:"crash_alert_interval"`
+
+	SHA256 string `json:"sha256"` // Expected hex SHA256 of the binary, checked before every start
+
+	RestartOnConfigChange bool `json:"restart_on_config_change"` // Restart when a reload changes this app's config
+	RestartOnBinaryChange bool `json:"restart_on_binary_change"` // Restart when a new binary is put in place at Path
+
+	MDNS bool `json:"mdns"` // Advertise the app on the LAN over mDNS while it runs
+
+	// Stopping sends StopSignal (default SIGTERM; e.g. SIGINT or SIGQUIT, with
+	// or without the SIG prefix) and kills the app if it is still running
+	// StopTimeout (default 10s) later. Apps run in their own process group,
+	// and both go to the whole group, so children the app spawned stop too.
+	StopSignal  string   `json:"stop_signal"`
+	StopTimeout Duration `json:"stop_timeout"`
+
+	ReloadSignal string `json:"reload_signal"` // Sent by the reload action so the app re-reads its own config; default SIGHUP
+
+	// When albert gets SIGTERM or SIGINT: "stop" (default) stops the app as
+	// above before albert exits, "detach" leaves it running on its own
+	OnManagerExit string `json:"on_manager_exit"`
+
+	// Where a detached app's output goes, since it outlives albert's pipes.
+	// albert follows the file for the app's output, and a later albert that
+	// adopts the app picks up where it is. Defaults to albert-<name>.out next
+	// to the state file, or in the temp directory without one; "/dev/null"
+	// discards the output.
+	OutputFile string `json:"output_file"`
+
+	// Directory the app runs in; albert's own when empty. A relative Path is
+	// resolved against it, and with Chroot it is inside the new root.
+	WorkDir string `json:"workdir"`
+
+	// Run the app as this user and group (names or numeric ids) instead of
+	// albert's own. Needs root or CAP_SETUID/CAP_SETGID. Group defaults to the
+	// user's primary group. The environment is still albert's.
+	User  string `json:"user"`
+	Group string `json:"group"`
+
+	// What to do when the process exits on its own: "always" restarts it,
+	// "on-failure" only after a non-zero exit or crash, "never" (default)
+	// leaves it stopped. Crashes are retried after RestartBackoff (default
+	// 1s), doubling per attempt up to RestartBackoffMax (default 1m), with
+	// jitter; the backoff starts over once the app has been up and healthy
+	// for StableAfter (default 30s). Clean exits under "always" are restarted
+	// after RestartDelay (default 1s).
+	Restart           string   `json:"restart"`
+	RestartDelay      Duration `json:"restart_delay"`
+	RestartBackoff    Duration `json:"restart_backoff"`
+	RestartBackoffMax Duration `json:"restart_backoff_max"`
+	StableAfter       Duration `json:"stable_after"`
+
+	// An app restarted after crashes more than CrashLoopRestarts times
+	// (default 5; negative disables) within CrashLoopWindow (default 5m) is
+	// quarantined: no more restarts or starts until POST /api/app/{name}/reset
+	CrashLoopRestarts int      `json:"crash_loop_restarts"`
+	CrashLoopWindow   Duration `json:"crash_loop_window"`
+
+	// restart-all leaves an app alone if albert restarted it less than this
+	// long ago, so bouncing the stack doesn't hit an app that just came back
+	RestartCooldown Duration `json:"restart_cooldown"`
+
+	// Deploys of apps with a DeployPort are blue/green: the new binary starts
+	// alongside the old one with {{.Port}} set to whichever of Port and
+	// DeployPort is free, and the old instance is stopped once the new one is
+	// ready. The two ports then trade places for the next deploy.
+	DeployPort int        `json:"deploy_port"`
+	alternate  *AppConfig // This config with the ports swapped, templates filled in for it
+
+	// Values for {{.Vars.key}} templates. Path, Args, the health URLs,
+	// EnvFile, WorkDir and Env values may also use {{.Name}} and {{.Port}}, so
+	// e.g. the port is written once and used in both Args and HealthURL.
+	Vars map[string]string `json:"vars"`
 }
 
 // Define the AppState structure to hold runtime information about each app
@@ -29,148 +208,719 @@ type AppState struct {
 	Config        AppConfig     `json:"config"`
 	Cmd           *exec.Cmd     `json:"-"` // Don't expose Cmd in JSON
 	Running       bool          `json:"running"`
+	Starting      bool          `json:"starting"` // Set while the process is being launched
+	Stopping      bool          `json:"stopping"` // Set while the process is given time to exit
+	Paused        bool          `json:"paused"`   // Suspended with SIGSTOP; still Running
+	Ready         bool          `json:"ready"`    // The current run has passed its readiness probe
+	liveFailures  int           // Failed liveness checks in a row
+	startTimedOut bool          // The current run was killed for not getting ready within StartTimeout
+	DiskLow       bool          `json:"disk_low"` // Free space is below MinFreeDiskMB
+	LastRequest   time.Time     `json:"last_request"` // Last proxied request, or when the app was started
+	RunCount      int           `json:"run_count"`    // Number of times the app has been started
+	StartedAt     time.Time     `json:"started_at"`   // When the current or last run started
+	Uptime        Duration      `json:"uptime"`       // How long the current run has been up; filled in for /api/apps
+	Restarts      map[string]int `json:"restarts"`    // Restarts performed by albert, by reason
+	RestartCount  int           `json:"restart_count"`  // Restarts performed by albert in total
+	LastExitCode  *int          `json:"last_exit_code"` // Of the last run; -1 if killed by a signal, null if unknown or never exited
+	stoppedCmd    *exec.Cmd     // Run being stopped by StopApp, whose exit is still recorded
+	exits         []ExitRecord  // Recent exits, oldest first
+	InBackoff      bool      `json:"in_backoff"`      // Waiting for an automatic restart
+	BackoffAttempt int       `json:"backoff_attempt"` // Crash restarts since the app was last stable
+	NextRestartAt  time.Time `json:"next_restart_at"` // When the pending automatic restart is due
+	restartTimer   *time.Timer // Pending automatic restart, if any
+	healthySince   time.Time   // Start of the current run's healthy streak
+	Quarantined    bool        `json:"quarantined"` // Crash-looping; not restarted until reset
+	Maintenance    bool        `json:"maintenance"` // Left alone by automatic and bulk restarts
+	lastRestart    time.Time   // When albert last restarted the app, for RestartCooldown
+	crashRestarts  []time.Time // Recent crash restarts, for crash-loop detection
+	binaryID       fileID      // Binary the current run started from, for RestartOnBinaryChange
+	deployMu       *sync.Mutex // Held for the whole of a deploy, so an app's deploys run one at a time
+	outputFile     string      // Where the current run writes its output; "" for pipes
+	exited        chan struct{}  // Closed when the latest run's process has exited
+	LogEntries    []LogEntry     `json:"-"` // Output lines for JSONLogs/TimestampPattern apps, oldest first
+	mdnsServer    *mdns.Server   // Set while the app is advertised over mDNS
+	RSSBytes      uint64         `json:"rss_bytes"` // Resident memory as of the last sample, 0 when stopped
+	lastCrashAlert    time.Time // When a crash was last announced
+	suppressedCrashes int       // Crashes since lastCrashAlert that weren't announced
 	HealthStatus  string        `json:"health_status"`
 	HealthLastCheck time.Time   `json:"health_last_check"`
-	OutputBuffer *bytes.Buffer `json:"-"` // Buffer to capture output
+	HealthDetail  string        `json:"health_detail,omitempty"` // Status value parsed from the health response body
+	HealthLatencyMS int64       `json:"health_latency_ms"`       // How long the last health check took
+	HealthCode    int           `json:"health_code"`             // Status code of the last health check
+	OutputBuffer  OutputBuffer  `json:"-"` // Recent output, retained per BufferStrategy
+	OutputLimit   int           `json:"output_limit"` // Share of the output budget in bytes
 	OutputChan    chan string   `json:"-"` // Channel to stream output
+	HealthHistory []HealthResult `json:"-"` // Recent health check outcomes and markers, oldest first
 }
 
+// defaultReadBufferSize is how much app output is read at once. Larger reads
+// mean fewer syscalls and lock round trips for chatty apps.
+const defaultReadBufferSize = 32 * 1024
+
+// defaultStopTimeout is how long a stopping app has to exit after SIGTERM
+// before it is killed
+const defaultStopTimeout = 10 * time.Second
+
+// defaultOutputBudget is the total number of bytes of output kept across all apps
+const defaultOutputBudget = 64 * 1024
+
 // Manager struct holds all application states and provides control
 type Manager struct {
-	apps map[string]*AppState
-	mu   sync.RWMutex
+	apps          map[string]*AppState
+	mu            sync.RWMutex
+	outputBudget  int
+	runMarkers    bool // Mark run boundaries in output instead of clearing it on start
+	actions       map[string]ActionConfig
+	crashArchive  *S3Config // Where crash output is uploaded, if configured
+	prerequisites []string  // External endpoints that must be reachable before bulk starts
+	memoryCeiling uint64    // Total app RSS in bytes above which apps are evicted; 0 disables
+	memoryUsed    uint64    // Total app RSS in bytes as of the last sample
+	configPath    string    // Config file reloaded by SIGHUP and /api/reload, if any
+	profile       string    // Active config profile, guarded by reloadMu; "" for none
+
+	scheduler *cron.Cron                    // Runs the apps' scheduled actions
+	scheduled map[cron.EntryID]ScheduledRun // What each scheduler entry does
+
+	jobsMu    sync.Mutex           // Guards the job fields, kept apart from mu since jobs write output often
+	jobTasks  map[string]JobConfig // Predefined one-shot tasks by name
+	jobs      []*Job               // Running and recently finished jobs, oldest first
+	nextJobID int
+	adhocJobs bool // Accept arbitrary commands on POST /api/jobs, not just jobTasks
+
+	reloadMu sync.Mutex      // Serializes config reloads
+	history  []ConfigVersion // Recent effective configs, oldest first, guarded by reloadMu
+
+	stateFile  string        // Where running apps' pids are kept for re-adoption, if set
+	stateDirty chan struct{} // Signals persistRuns to rewrite stateFile
+
+	done         chan struct{} // Closed by Shutdown to stop background goroutines
+	shutdownOnce sync.Once
 }
 
 // NewManager creates and initializes a new Manager instance
 func NewManager(configs []AppConfig) *Manager {
 	m := &Manager{
-		apps: make(map[string]*AppState),
+		apps:         make(map[string]*AppState),
+		outputBudget: defaultOutputBudget,
+		runMarkers:   true,
+		done:         make(chan struct{}),
 	}
+	boot := time.Now()
 	for _, cfg := range configs {
-		m.apps[cfg.Name] = &AppState{
-			Config:        cfg,
-			Running:       false,
-			HealthStatus:  "Unknown",
-			OutputBuffer:  new(bytes.Buffer),
-			OutputChan:    make(chan string, 100), // Buffered channel for output
-		}
+		app := newAppState(cfg)
+		app.HealthHistory = []HealthResult{{Time: boot, Marker: markerAlbertStarted}}
+		m.apps[cfg.Name] = app
 	}
+	m.rebalanceOutputBuffers()
 	return m
 }
 
-// StartApp starts a specified application
-func (m *Manager) StartApp(appName string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// newAppState creates the state for a newly managed app. Its output limit is
+// set by the next rebalanceOutputBuffers.
+func newAppState(cfg AppConfig) *AppState {
+	output, err := newOutputBuffer(cfg)
+	if err != nil {
+		appLogf(cfg, "%v, keeping output by bytes", err)
+		output = &byteBuffer{}
+	}
+	if cfg.HealthInsecureSkipVerify {
+		appLogf(cfg, "Warning: health checks for %s skip TLS certificate verification", cfg.Name)
+	}
+	return &AppState{
+		Config:       cfg,
+		Running:      false,
+		HealthStatus: "Unknown",
+		OutputBuffer: output,
+		OutputChan:   make(chan string, 100), // Buffered channel for output
+		deployMu:     &sync.Mutex{},
+	}
+}
 
-	app, ok := m.apps[appName]
-	if !ok {
-		return fmt.Errorf("app %s not found", appName)
+// rebalanceOutputBuffers splits the output budget across apps proportional to
+// their Priority and trims any buffer that no longer fits. Must be called with
+// m.mu held, and again whenever apps are added or removed.
+func (m *Manager) rebalanceOutputBuffers() {
+	totalWeight := 0
+	for _, app := range m.apps {
+		totalWeight += outputWeight(app.Config)
 	}
-	if app.Running {
-		return fmt.Errorf("app %s is already running", appName)
+	if totalWeight == 0 {
+		return
+	}
+	for _, app := range m.apps {
+		app.OutputLimit = m.outputBudget * outputWeight(app.Config) / totalWeight
+		app.OutputBuffer.SetLimit(app.OutputLimit)
+	}
+}
+
+// outputWeight returns an app's share weight for the output budget
+func outputWeight(cfg AppConfig) int {
+	if cfg.Priority <= 0 {
+		return 1
 	}
+	return cfg.Priority
+}
 
-	cmd := exec.Command(app.Config.Path, app.Config.Args...)
+// outputSnapshot returns a copy of the buffered output that stays valid after
+// the lock is released. Must be called with m.mu held.
+func (app *AppState) outputSnapshot() []byte {
+	return app.OutputBuffer.Bytes()
+}
 
-	// Capture stdout and stderr
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe for %s: %w", appName, err)
+// writeRunMarker appends a line to the output buffer marking the start of a
+// new run. Must be called with m.mu held.
+func (app *AppState) writeRunMarker() {
+	if out := app.OutputBuffer.Bytes(); len(out) > 0 && out[len(out)-1] != '\n' {
+		app.OutputBuffer.Write([]byte{'\n'})
 	}
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stderr pipe for %s: %w", appName, err)
+	verb := "restarted"
+	if app.RunCount == 1 {
+		verb = "started"
 	}
+	fmt.Fprintf(app.OutputBuffer, "--- %s %s at %s (run #%d) ---\n", app.Config.Name, verb, time.Now().Format(time.RFC3339), app.RunCount)
+}
 
-	// Combined output reader
-	multiReader := io.MultiReader(stdoutPipe, stderrPipe)
+// StartApp starts a specified application, after starting any apps it
+// depends on that aren't running and waiting for them to become ready
+func (m *Manager) StartApp(appName string) error {
+	return m.startWithDependencies(appName, nil)
+}
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start app %s: %w", appName, err)
+// launchStarted launches an app that has been marked Starting, and clears
+// Starting once the process is up or failed to start
+func (m *Manager) launchStarted(app *AppState, cfg AppConfig) error {
+	appName := cfg.Name
+
+	// Resolve secrets and launch without holding the lock; Starting keeps
+	// other callers out
+	var cmd *exec.Cmd
+	var multiReader io.Reader
+	resolved, redact, err := resolveSecrets(cfg)
+	if err == nil {
+		cmd, multiReader, err = launchApp(resolved, m.detachedOutput(resolved))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	app.Starting = false
+	if err != nil {
+		if errors.Is(err, errChecksumMismatch) {
+			app.HealthStatus = "ChecksumMismatch"
+			appLogf(cfg, "Refusing to start %s: %v", appName, err)
+		}
+		return err
 	}
 
 	app.Cmd = cmd
 	app.Running = true
-	app.OutputBuffer.Reset() // Clear buffer on restart
-
-	// Goroutine to continuously read process output
-	go func(appName string, reader io.Reader) {
-		buf := make([]byte, 1024)
-		for {
-			n, err := reader.Read(buf)
-			if n > 0 {
-				line := string(buf[:n])
-				m.mu.Lock()
-				app.OutputBuffer.WriteString(line) // Write to buffer
-				// Keep buffer size reasonable
-				if app.OutputBuffer.Len() > 4096 {
-					app.OutputBuffer = bytes.NewBuffer(app.OutputBuffer.Bytes()[app.OutputBuffer.Len()-2048:])
+	app.StartedAt = time.Now()
+	app.outputFile = m.detachedOutput(resolved)
+	app.healthySince = time.Time{}
+	if path := watchedBinary(cfg); path != "" {
+		app.binaryID, _ = statFileID(path) // The version this run started from
+	}
+	app.Paused = false
+	app.Ready = cfg.readyURL() == ""
+	app.liveFailures = 0
+	app.startTimedOut = false
+	if !app.Ready {
+		app.HealthStatus = healthStarting // Health checks wait for the readiness probe
+		go m.gateReady(app, cfg, cmd)
+	}
+	app.LastRequest = app.StartedAt // Start the idle clock
+	app.RunCount++
+	m.runsChanged()
+	if m.runMarkers {
+		app.writeRunMarker() // Keep the previous run's output, separated by a marker
+	} else {
+		app.OutputBuffer.Reset() // Clear buffer on restart
+	}
+
+	go m.readOutput(app, cfg, multiReader, redact)
+	app.exited = m.watchExit(app, cmd)
+
+	if cfg.MDNS {
+		go m.advertiseApp(app, cfg, cmd)
+	}
+
+	hub.Publish(EventAppState, app.stateEvent())
+	appLogf(app.Config, "Started app: %s", appName)
+	return nil
+}
+
+// readOutput copies a run's output into the app's buffer and streams it to
+// subscribers until the output ends
+func (m *Manager) readOutput(app *AppState, cfg AppConfig, reader io.Reader, redact *strings.Replacer) {
+	appName := cfg.Name
+	var fifo *fifoWriter
+	if cfg.FIFOPath != "" {
+		var err error
+		if fifo, err = newFIFOWriter(cfg.FIFOPath); err != nil {
+			appLogf(cfg, "Error creating output FIFO for %s: %v", appName, err)
+		}
+	}
+	defer func() {
+		if fifo != nil {
+			fifo.Close() // Remove the FIFO once the app's output ends
+		}
+	}()
+
+	size := cfg.ReadBufferSize
+	if size <= 0 {
+		size = defaultReadBufferSize
+	}
+	// Apps that need per-line handling get their output split into entries
+	var lines *lineSplitter
+	var timestamps *timestampExtractor
+	if cfg.TimestampPattern != "" {
+		var err error
+		if timestamps, err = newTimestampExtractor(cfg); err != nil {
+			appLogf(cfg, "%v", err)
+		}
+	}
+	if cfg.JSONLogs || timestamps != nil {
+		lines = &lineSplitter{}
+	}
+
+	buf := make([]byte, size)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			if redact != nil { // A secret split across reads slips through
+				chunk = []byte(redact.Replace(string(chunk)))
+			}
+			line := string(chunk)
+			if fifo != nil {
+				if _, err := fifo.Write(chunk); err != nil {
+					appLogf(cfg, "Error writing output FIFO for %s, disabling it: %v", appName, err)
+					fifo.Close()
+					fifo = nil
 				}
-				m.mu.Unlock()
+			}
+
+			// Structured apps stream one JSON entry per line instead of raw chunks
+			messages := []string{line}
+			if cfg.JSONLogs {
+				messages = messages[:0]
+			}
+			var entries []LogEntry
+			if lines != nil {
+				now := time.Now()
+				for _, l := range lines.Feed(chunk) {
+					entry := LogEntry{Time: now, Msg: l, Raw: l}
+					if cfg.JSONLogs {
+						entry = parseJSONLogLine(l, now)
+					}
+					if timestamps != nil {
+						if t, ok := timestamps.Extract(l); ok {
+							entry.Time = t
+						}
+					}
+					entries = append(entries, entry)
+					if cfg.JSONLogs {
+						if b, err := json.Marshal(entry); err == nil {
+							messages = append(messages, string(b))
+						}
+					}
+				}
+			}
+
+			m.mu.Lock()
+			app.OutputBuffer.Write(chunk) // Trimmed to its share of the budget as it goes
+			app.appendLogEntries(entries)
+			m.mu.Unlock()
+			select {
+			case <-m.done:
+				// Manager is shutting down; stop streaming, but keep draining
+				// the pipe so an app that is being stopped never blocks on it
+				messages = nil
+			default:
+			}
+			for _, msg := range messages {
+				hub.Publish(EventAppOutput, AppOutputEvent{App: appName, Output: msg})
 				select {
-				case app.OutputChan <- line: // Send to channel for streaming if needed
+				case app.OutputChan <- msg: // Send to channel for streaming if needed
 				default:
 					// Drop if channel is full
 				}
 			}
-			if err != nil {
-				if err != io.EOF {
-					log.Printf("Error reading output from %s: %v", appName, err)
-				}
-				break
+		}
+		if err != nil {
+			if err != io.EOF {
+				appLogf(app.Config, "Error reading output from %s: %v", appName, err)
 			}
+			break
 		}
-	}(appName, multiReader)
+	}
+}
 
-	// Goroutine to wait for the process to exit
-	go func(appName string, cmd *exec.Cmd) {
+// watchExit waits for a run's process to exit and reports the exit. The
+// returned channel is closed once the process has exited.
+func (m *Manager) watchExit(app *AppState, cmd *exec.Cmd) chan struct{} {
+	exited := make(chan struct{})
+	started := time.Now()
+	go func() {
 		err := cmd.Wait()
-		m.mu.Lock()
-		defer m.mu.Unlock()
-		if app.Cmd == cmd { // Ensure it's the current command for this app
-			app.Running = false
-			app.Cmd = nil
-			if err != nil {
-				log.Printf("App %s exited with error: %v", appName, err)
-				app.HealthStatus = fmt.Sprintf("Exited: %v", err)
-			} else {
-				log.Printf("App %s exited normally.", appName)
-				app.HealthStatus = "Stopped"
+		close(exited)
+		m.reportExit(app, cmd, started, err)
+	}()
+	return exited
+}
+
+// reportExit records that a run's process, started at started, exited with
+// err. The exit is handled if cmd is still the app's current command by then,
+// and only recorded if StopApp is stopping it.
+func (m *Manager) reportExit(app *AppState, cmd *exec.Cmd, started time.Time, err error) {
+	appName := app.Config.Name
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if app.stoppedCmd == cmd {
+		app.recordExit(err, started)
+		app.stoppedCmd = nil
+	}
+	if app.Cmd == cmd { // Ensure it's the current command for this app
+		m.runsChanged()
+		app.Running = false
+		app.Paused = false
+		app.Ready = false
+		app.Cmd = nil
+		app.withdrawMDNS()
+		app.recordExit(err, started)
+		timedOut := app.startTimedOut
+		if timedOut {
+			err = fmt.Errorf("not ready within start_timeout %s", time.Duration(app.Config.StartTimeout))
+			app.startTimedOut = false
+		}
+		if err != nil {
+			appLogf(app.Config, "App %s exited with error: %v", appName, err)
+			app.HealthStatus = fmt.Sprintf("Exited: %v", err)
+			if event, ok := app.crashAlert(err, time.Now()); ok {
+				go emitCriticalEvent(event)
+				hub.Publish(EventCrash, event)
+			}
+			if app.Config.MaxOpenFiles > 0 && ranOutOfFiles(app.OutputBuffer.Bytes()) {
+				appLogf(app.Config, "App %s appears to have run out of file descriptors (max_open_files %d)", appName, app.Config.MaxOpenFiles)
+				app.HealthStatus += " (out of file descriptors)"
+			}
+			if m.crashArchive != nil {
+				go m.crashArchive.archiveCrash(appName, app.outputSnapshot(), time.Now())
 			}
+		} else {
+			appLogf(app.Config, "App %s exited normally.", appName)
+			app.HealthStatus = "Stopped"
 		}
-	}(appName, cmd)
+		if timedOut {
+			app.HealthStatus = healthStartTimeout
+		}
+		exit := AppExitedEvent{App: appName}
+		if err != nil {
+			exit.Error = err.Error()
+		}
+		if err != nil && app.isStable(time.Now()) {
+			app.BackoffAttempt = 0 // Crashed after a stable run; back off from the start
+		}
+		m.scheduleRestart(app, err)
+		hub.Publish(EventAppExited, exit)
+		hub.Publish(EventAppState, app.stateEvent())
+	}
+}
 
-	log.Printf("Started app: %s", appName)
-	return nil
+// exitCode returns the exit code a process's Wait error carries: 0 for a
+// clean exit, -1 if it was killed by a signal, or nil if it isn't known
+func exitCode(err error) *int {
+	code := 0
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		code = exitErr.ExitCode()
+	default:
+		return nil
+	}
+	return &code
 }
 
-// StopApp stops a specified application
-func (m *Manager) StopApp(appName string) error {
+// waitExited blocks until the app's latest process has fully exited or ctx
+// is done
+func (m *Manager) waitExited(ctx context.Context, appName string) error {
+	m.mu.RLock()
+	var exited chan struct{}
+	if app, ok := m.apps[appName]; ok {
+		exited = app.exited
+	}
+	m.mu.RUnlock()
+	if exited == nil {
+		return nil // Never started
+	}
+
+	select {
+	case <-exited:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("app %s did not exit: %w", appName, ctx.Err())
+	}
+}
+
+// Shutdown stops the manager's background goroutines. Output readers stop
+// streaming instead of sending to subscribers that may be gone. It is safe to
+// call more than once.
+func (m *Manager) Shutdown() {
+	m.shutdownOnce.Do(func() {
+		close(m.done)
+		m.mu.Lock()
+		if m.scheduler != nil {
+			m.scheduler.Stop()
+		}
+		m.mu.Unlock()
+	})
+}
+
+// Errors from beginStart for an app that another start got to first
+var (
+	errAlreadyStarting = errors.New("already starting")
+	errAlreadyRunning  = errors.New("already running")
+)
+
+// beginStart marks an app as starting so concurrent StartApp calls are
+// rejected while the process is being launched. It returns a copy of the
+// app's config to launch with.
+func (m *Manager) beginStart(appName string) (*AppState, AppConfig, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	app, ok := m.apps[appName]
 	if !ok {
+		return nil, AppConfig{}, fmt.Errorf("app %s not found", appName)
+	}
+	if app.Starting {
+		return nil, AppConfig{}, fmt.Errorf("app %s is %w", appName, errAlreadyStarting)
+	}
+	if app.Stopping {
+		return nil, AppConfig{}, fmt.Errorf("app %s is still stopping", appName)
+	}
+	if app.Running {
+		return nil, AppConfig{}, fmt.Errorf("app %s is %w", appName, errAlreadyRunning)
+	}
+	if app.Quarantined {
+		return nil, AppConfig{}, fmt.Errorf("app %s is %w", appName, errQuarantined)
+	}
+	app.cancelRestart() // Started by hand ahead of a pending automatic restart
+	app.Starting = true
+	return app, app.Config, nil
+}
+
+// launchApp creates and starts the process for an app, returning the command
+// and a reader over its combined output. With outputFile the app writes its
+// output there rather than to pipes, and the reader follows the file.
+func launchApp(cfg AppConfig, outputFile string) (*exec.Cmd, io.Reader, error) {
+	appName := cfg.Name
+	env, err := appEnv(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if missing := missingEnv(cfg.RequiredEnv, env); len(missing) > 0 {
+		return nil, nil, fmt.Errorf("app %s is missing required environment variables: %s", appName, strings.Join(missing, ", "))
+	}
+	if err := checkDiskSpace(cfg); err != nil {
+		return nil, nil, err
+	}
+	if err := verifyChecksum(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	sandbox, err := sandboxAttr(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	cred, err := appCredential(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd := appCommand(cfg)
+	cmd.Env = env
+	if sandbox != nil {
+		cmd.SysProcAttr = sandbox
+		if sandbox.Chroot != "" {
+			cmd.Dir = "/" // albert's own working directory may not exist inside the chroot
+		}
+	}
+	if cfg.WorkDir != "" {
+		cmd.Dir = cfg.WorkDir
+	}
+	if cred != nil {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Credential = cred
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true // Lets StopApp kill the children the app spawned
+	if cfg.MaxOpenFiles > 0 {
+		if err := limitOpenFiles(cmd, cfg, cfg.MaxOpenFiles); err != nil {
+			return nil, nil, fmt.Errorf("failed to limit open files for %s: %w", appName, err)
+		}
+	}
+
+	// Capture stdout and stderr
+	var multiReader io.Reader
+	var follower *outputFollower
+	switch outputFile {
+	case "":
+		stdoutPipe, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get stdout pipe for %s: %w", appName, err)
+		}
+		stderrPipe, err := cmd.StderrPipe()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get stderr pipe for %s: %w", appName, err)
+		}
+		multiReader = io.MultiReader(stdoutPipe, stderrPipe) // Combined output reader
+	case os.DevNull:
+		multiReader = strings.NewReader("") // Left nil, Stdout and Stderr are /dev/null
+	default:
+		out, err := os.OpenFile(outputFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open output file for %s: %w", appName, err)
+		}
+		defer out.Close() // The app has its own copy once started
+		cmd.Stdout, cmd.Stderr = out, out
+		if follower, err = followOutput(outputFile); err != nil {
+			return nil, nil, fmt.Errorf("failed to follow output file for %s: %w", appName, err)
+		}
+		multiReader = follower
+	}
+
+	if cfg.Singleton {
+		lock, err := acquireSingletonLock(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The child inherits the locked file; our copy is closed once it has started
+		defer lock.Close()
+		cmd.ExtraFiles = append(cmd.ExtraFiles, lock)
+	}
+
+	if err := cmd.Start(); err != nil {
+		if follower != nil {
+			follower.f.Close()
+		}
+		return nil, nil, fmt.Errorf("failed to start app %s: %w", appName, err)
+	}
+	if follower != nil {
+		follower.watch(cmd.Process.Pid)
+	}
+	return cmd, multiReader, nil
+}
+
+// StopApp stops a specified application, returning once its process has
+// exited
+func (m *Manager) StopApp(appName string) error {
+	m.mu.Lock()
+	app, ok := m.apps[appName]
+	if !ok {
+		m.mu.Unlock()
 		return fmt.Errorf("app %s not found", appName)
 	}
+	if app.Stopping {
+		m.mu.Unlock()
+		return fmt.Errorf("app %s is already stopping", appName)
+	}
+	if app.InBackoff && !app.Running {
+		app.cancelRestart()
+		hub.Publish(EventAppState, app.stateEvent())
+		m.mu.Unlock()
+		appLogf(app.Config, "Cancelled the pending restart of %s", appName)
+		return nil
+	}
 	if !app.Running || app.Cmd == nil || app.Cmd.Process == nil {
+		m.mu.Unlock()
 		return fmt.Errorf("app %s is not running", appName)
 	}
 
-	if err := app.Cmd.Process.Kill(); err != nil {
-		return fmt.Errorf("failed to kill app %s: %w", appName, err)
-	}
+	// The process may already be exiting on its own. Its wait goroutine
+	// reports the exit only while app.Cmd is still its command, so clearing
+	// Cmd here makes this the single report either way. Stopping keeps new
+	// starts out until the process is gone.
+	cfg, proc, exited, paused := app.Config, app.Cmd.Process, app.exited, app.Paused
 	app.Running = false
-	app.HealthStatus = "Stopped"
+	app.Paused = false
+	app.Ready = false
+	app.Stopping = true
+	app.HealthStatus = "Stopping"
+	app.stoppedCmd = app.Cmd
 	app.Cmd = nil // Clear command reference
-	log.Printf("Stopped app: %s", appName)
+	app.withdrawMDNS()
+	hub.Publish(EventAppState, app.stateEvent())
+	m.mu.Unlock()
+
+	err := stopProcess(cfg, proc, exited, paused)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	app.Stopping = false
+	app.HealthStatus = "Stopped"
+	m.runsChanged()
+	hub.Publish(EventAppState, app.stateEvent())
+	if err != nil {
+		return fmt.Errorf("failed to stop app %s: %w", appName, err)
+	}
+	appLogf(app.Config, "Stopped app: %s", appName)
+	return nil
+}
+
+// stopProcess asks an app's process to exit with its stop signal and kills
+// it if it hasn't exited after the app's StopTimeout. exited is closed once the
+// process has exited. Children can outlive the app's own process, so its
+// group is killed after it exits too. A paused app is continued right after
+// the stop signal so it can act on it.
+func stopProcess(cfg AppConfig, proc *os.Process, exited <-chan struct{}, paused bool) error {
+	select {
+	case <-exited:
+		if err := killApp(proc); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return err
+		}
+		return nil
+	default:
+	}
+
+	timeout := time.Duration(cfg.StopTimeout)
+	if timeout <= 0 {
+		timeout = defaultStopTimeout
+	}
+	sig, err := cfg.stopSignal()
+	if err != nil {
+		appLogf(cfg, "App %s has %v, stopping it with SIGTERM", cfg.Name, err)
+		sig = syscall.SIGTERM
+	}
+	if err := signalApp(proc, sig); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	if paused {
+		if err := signalApp(proc, syscall.SIGCONT); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return err
+		}
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-exited:
+	case <-timer.C:
+		appLogf(cfg, "App %s did not exit within %s of %s, killing it", cfg.Name, timeout, signalName(sig))
+	}
+	if err := killApp(proc); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	<-exited
 	return nil
 }
 
-// CheckAppHealth performs a health check on a specific app's HealthURL
+// CheckAppHealth performs a health check on a specific app's liveness probe
 func (m *Manager) CheckAppHealth(app *AppState) {
-	if app.Config.HealthURL == "" {
+	if app.Config.liveURL() == "" {
 		m.mu.Lock()
 		app.HealthStatus = "N/A"
 		app.HealthLastCheck = time.Now()
@@ -178,34 +928,52 @@ func (m *Manager) CheckAppHealth(app *AppState) {
 		return
 	}
 
-	client := http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(app.Config.HealthURL)
+	start := time.Now()
+	result, err := probeHealth(app.Config.probeFor(app.Config.liveURL()))
+	latency := time.Since(start)
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	app.HealthLastCheck = time.Now()
+	defer app.recordHealth(latency) // Runs before unlock, once HealthStatus is final
 	if err != nil {
 		app.HealthStatus = fmt.Sprintf("Error: %v", err)
-		log.Printf("Health check for %s failed: %v", app.Config.Name, err)
+		app.HealthCode = 0
+		app.HealthDetail = ""
+		app.HealthLatencyMS = latency.Milliseconds()
+		appLogf(app.Config, "Health check for %s failed: %v", app.Config.Name, err)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusOK {
-		app.HealthStatus = "Healthy"
-	} else {
-		app.HealthStatus = fmt.Sprintf("Degraded (%d)", resp.StatusCode)
+	app.HealthStatus = result.Status
+	app.HealthCode = result.Code
+	app.HealthDetail = result.Detail
+	app.HealthLatencyMS = latency.Milliseconds()
+	if slow := time.Duration(app.Config.HealthSlowThreshold); slow > 0 && latency > slow && result.Status == "Healthy" {
+		app.HealthStatus = fmt.Sprintf("Slow (%dms)", latency.Milliseconds())
 	}
-	log.Printf("Health check for %s: %s (Status: %d)", app.Config.Name, app.HealthStatus, resp.StatusCode)
+	appLogf(app.Config, "Health check for %s: %s (Status: %d)", app.Config.Name, app.HealthStatus, result.Code)
 }
 
-// RunHealthChecks periodically runs health checks for all apps
+// RunHealthChecks periodically runs health checks for all apps. Sending
+// albert SIGUSR1 runs an extra sweep right away without resetting the ticker.
 func (m *Manager) RunHealthChecks(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
+	sweep := make(chan os.Signal, 1)
+	signal.Notify(sweep, syscall.SIGUSR1)
+	defer signal.Stop(sweep)
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		case <-sweep:
+			log.Printf("Manual health sweep requested")
+		}
 
-	for range ticker.C {
 		m.mu.RLock()
 		appsToHealthCheck := []*AppState{}
 		for _, app := range m.apps {
@@ -213,12 +981,33 @@ func (m *Manager) RunHealthChecks(interval time.Duration) {
 		}
 		m.mu.RUnlock()
 
+		m.stopIdleApps()
+		m.enforceMemoryCeiling()
 		for _, app := range appsToHealthCheck {
+			m.updateDiskStatus(app)
+			if app.Paused {
+				continue // Checked again once resumed
+			}
 			if app.Running { // Only check health of running apps
+				m.mu.RLock()
+				due := app.liveCheckDue(time.Now())
+				m.mu.RUnlock()
+				if !due {
+					continue
+				}
 				m.CheckAppHealth(app)
+				m.mu.Lock()
+				app.noteStability(time.Now())
+				restart := app.noteLiveness()
+				m.mu.Unlock()
+				if restart {
+					go m.restartUnlive(app.Config.Name)
+				}
 			} else {
 				m.mu.Lock()
-				app.HealthStatus = "Stopped"
+				if app.HealthStatus != "ChecksumMismatch" && app.HealthStatus != healthStartTimeout && !app.Quarantined { // Kept until a start succeeds or a reset
+					app.HealthStatus = "Stopped"
+				}
 				app.HealthLastCheck = time.Now()
 				m.mu.Unlock()
 			}
@@ -229,7 +1018,8 @@ func (m *Manager) RunHealthChecks(interval time.Duration) {
 func handleMessage(message twitch_types.Message)(string) {
 	json, _ := json.Marshal(message)
 	bytes := []byte(json)
-	sse.SendBytes(bytes)
+	sseEvents.Send(bytes)
+	hub.Publish(EventChat, message)
 	return ""
 }
 
@@ -241,7 +1031,11 @@ func getAppsHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
 
 	states := make([]AppState, 0, len(mgr.apps))
 	for _, app := range mgr.apps {
-		states = append(states, *app)
+		state := *app
+		if state.Running {
+			state.Uptime = Duration(time.Since(state.StartedAt).Round(time.Second))
+		}
+		states = append(states, state)
 	}
 
 	if err := json.NewEncoder(w).Encode(states); err != nil {
@@ -250,7 +1044,9 @@ func getAppsHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// controlAppHandler handles start/stop requests for an app
+// controlAppHandler handles start/stop/restart/pause/resume requests for an
+// app. Stopping an app that running apps depend on is refused with 409
+// unless ?cascade=true, which stops those apps first.
 func controlAppHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
 	appName := r.URL.Path[len("/api/app/"):] // Extract app name from URL
 	var action string
@@ -266,9 +1062,30 @@ func controlAppHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
 	case "start":
 		err = mgr.StartApp(appName)
 	case "stop":
-		err = mgr.StopApp(appName)
+		err = mgr.StopWithDependents(appName, r.URL.Query().Get("cascade") == "true")
+		var dependents *DependentsError
+		if errors.As(err, &dependents) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			resp := map[string]any{
+				"error":      err.Error() + "; stop them first or use cascade=true",
+				"dependents": dependents.Dependents,
+			}
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				log.Printf("Error encoding stop refusal for %s: %v", appName, err)
+			}
+			return
+		}
+	case "restart":
+		err = mgr.RestartApp(appName, RestartManual)
+	case "pause":
+		err = mgr.PauseApp(appName)
+	case "resume":
+		err = mgr.ResumeApp(appName)
+	case "reload":
+		err = mgr.ReloadApp(appName)
 	default:
-		http.Error(w, "Invalid action. Must be 'start' or 'stop'.", http.StatusBadRequest)
+		http.Error(w, "Invalid action. Must be 'start', 'stop', 'restart', 'pause', 'resume' or 'reload'.", http.StatusBadRequest)
 		return
 	}
 
@@ -282,11 +1099,24 @@ func controlAppHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "{\"status\": \"success\", \"message\": \"%s app %s\"}", action, appName)
 }
 
-// getAppOutputHandler returns the last N lines of output for a given app
+// getAppOutputHandler returns the last N lines of output for a given app.
+// With ?format=json it returns per-line log entries instead, optionally
+// filtered to ?level= and above.
 func getAppOutputHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
 	appName := r.URL.Path[len("/api/output/"):] // Extract app name from URL
+	asJSON := r.URL.Query().Get("format") == "json"
+	// Look up the app and copy its output in one critical section, since the
+	// reader goroutine writes to OutputBuffer concurrently
 	mgr.mu.RLock()
 	app, ok := mgr.apps[appName]
+	var output []byte
+	var entries []LogEntry
+	if ok {
+		output = app.outputSnapshot()
+		if asJSON {
+			entries = append([]LogEntry{}, app.LogEntries...)
+		}
+	}
 	mgr.mu.RUnlock()
 
 	if !ok {
@@ -294,13 +1124,18 @@ func getAppOutputHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(filterLogEntries(entries, r.URL.Query().Get("level"))); err != nil {
+			log.Printf("Error encoding log entries for %s: %v", appName, err)
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/plain")
-	mgr.mu.RLock()
-	output := app.OutputBuffer.String()
-	mgr.mu.RUnlock()
 
 	// Simple way to get last lines, could be improved
-	lines := bytes.Split([]byte(output), []byte("\n"))
+	lines := bytes.Split(output, []byte("\n"))
 	numLines := len(lines)
 	start := 0
 	if numLines > 50 { // Limit to last 50 lines
@@ -312,48 +1147,269 @@ func getAppOutputHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
 	w.Write(filteredOutput)
 }
 
-func main() {
-	log.Println("Starting Go App Manager...")
-
-	// Define your applications here
+// loadStartupConfigs builds the app list albert starts with: the built-in
+// list, or configPath with profile applied, overlaid with APP_<n>_*
+// environment variables, with templates and ${VAR} references filled in
+func loadStartupConfigs(configPath, profile string) ([]AppConfig, error) {
+	// Define your applications here, or pass --config to load them from a file
 	// Ensure that 'path' points to your compiled Go binaries.
 	// For example, if you have 'my-go-app' in the same directory, use "./my-go-app"
 	// Or a full path like "/usr/local/bin/my-go-app"
 	// Replace "http://localhost:8081/health" with the actual health check URL for your apps.
 	appConfigs := []AppConfig{
-		{Name: "Cacaphony", Path: "/home/tommy/cacaphony/cacaphony", Args: []string{"--port", "6972"}, HealthURL: "http://127.0.0.1:6972/health"},
-		{Name: "Heckler", Path: "/home/tommy/heckler/heckler", Args: []string{"--port", "6971"}, HealthURL: "http://127.0.0.1:6971/health"},
-		{Name: "K Facts", Path: "/home/tommy/k_facts/k_facts", Args: []string{"--port", "6974"}, HealthURL: "http://127.0.0.1:6974/ping"},
-		{Name: "Noise Machine", Path: "/home/tommy/noise_machine/noise_machine", Args: []string{"--port", "6976"}, HealthURL: "http://127.0.0.1:6976/health"},
-		{Name: "Trombone", Path: "/home/tommy/trombone/trombone", Args: []string{"--port", "6973"}, HealthURL: "http://127.0.0.1:6973/health"},
+		{Name: "Cacaphony", Path: "/home/tommy/cacaphony/cacaphony", Args: []string{"--port", "{{.Port}}"}, HealthURL: "http://127.0.0.1:{{.Port}}/health", Port: 6972},
+		{Name: "Heckler", Path: "/home/tommy/heckler/heckler", Args: []string{"--port", "{{.Port}}"}, HealthURL: "http://127.0.0.1:{{.Port}}/health", Port: 6971},
+		{Name: "K Facts", Path: "/home/tommy/k_facts/k_facts", Args: []string{"--port", "{{.Port}}"}, HealthURL: "http://127.0.0.1:{{.Port}}/ping", Port: 6974},
+		{Name: "Noise Machine", Path: "/home/tommy/noise_machine/noise_machine", Args: []string{"--port", "{{.Port}}"}, HealthURL: "http://127.0.0.1:{{.Port}}/health", Port: 6976},
+		{Name: "Trombone", Path: "/home/tommy/trombone/trombone", Args: []string{"--port", "{{.Port}}"}, HealthURL: "http://127.0.0.1:{{.Port}}/health", Port: 6973},
+	}
+	if configPath != "" {
+		fileConfig, err := loadConfigFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+		if appConfigs, err = fileConfig.applyProfile(profile); err != nil {
+			return nil, err
+		}
+		log.Printf("Loaded %d apps from %s", len(appConfigs), configPath)
+	} else if profile != "" {
+		return nil, fmt.Errorf("--profile needs a --config file that defines profiles")
+	}
+
+	// Apps defined through APP_<n>_* environment variables override or extend the list above
+	envConfigs, err := configsFromEnv(os.Environ())
+	if err != nil {
+		return nil, fmt.Errorf("invalid app configuration in environment: %w", err)
+	}
+	appConfigs, err = expandConfigs(mergeConfigs(appConfigs, envConfigs))
+	if err != nil {
+		return nil, fmt.Errorf("invalid app configuration: %w", err)
+	}
+	return appConfigs, nil
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "schema":
+			if err := writeConfigSchema(os.Stdout); err != nil {
+				log.Fatalf("Failed to write config schema: %v", err)
+			}
+			return
+		}
 	}
 
+	runMarkers := flag.Bool("run-markers", true, "mark run boundaries in app output instead of clearing it on start")
+	memoryCeilingMB := flag.Uint64("memory-ceiling-mb", 0, "stop the lowest priority app while all apps together use more memory than this (0 disables)")
+	sseWindow := flag.Duration("sse-batch-window", 0, "send SSE events arriving within this window together as one JSON array (0 sends each right away)")
+	maxEventClients := flag.Int("max-event-clients", defaultMaxEventClients, "refuse /events connections beyond this many with 503 (0 for no limit)")
+	configPath := flag.String("config", "", "load app definitions from this JSON, YAML or TOML file, or a directory of per-app files, instead of the built-in list")
+	validatePath := flag.String("validate", "", "check this config file, print any problems and exit")
+	profile := flag.String("profile", "", "apply this profile from the config file, e.g. dev or streaming")
+	autostartWait := flag.Bool("autostart-wait-ready", false, "on autostart, wait for each start_order tier to become ready before starting the next")
+	applyConfigChanges := flag.Bool("apply-config-changes", false, "reload the config file as soon as it changes instead of waiting for POST /api/reload")
+	stateFile := flag.String("state-file", "", "remember running apps' pids in this file and re-adopt those still running when albert restarts")
+	allowAdhocJobs := flag.Bool("allow-adhoc-jobs", false, "let POST /api/jobs run any command it is sent, not just the predefined tasks")
+	listen := flag.String("listen", "127.0.0.1:6978", "address to serve the API on; use :6978 to accept connections from other hosts")
+	flag.Parse()
+
+	if *validatePath != "" {
+		report := validateConfigFile(*validatePath, *profile, nil)
+		printValidationReport(os.Stdout, report)
+		if !report.Valid {
+			os.Exit(1)
+		}
+		return
+	}
+
+	log.Println("Starting Go App Manager...")
+
+	appConfigs, err := loadStartupConfigs(*configPath, *profile)
+	if err != nil {
+		log.Fatalf("Failed to load apps: %v", err)
+	}
+
+	// Named action macros, run with POST /api/action/{name}
+	actionConfigs := []ActionConfig{
+		{Name: "bounce-noise", Steps: []ActionStep{
+			{Op: StepStop, App: "Trombone"},
+			{Op: StepRestart, App: "Noise Machine"},
+			{Op: StepWaitHealthy, App: "Noise Machine", Duration: Duration(30 * time.Second)},
+			{Op: StepStart, App: "Trombone"},
+		}},
+	}
+
+	// Predefined one-shot tasks, run with POST /api/jobs {"task": "<name>"}
+	jobTasks := []JobConfig{}
+
+	// External services (http(s):// or tcp://host:port) that must be reachable
+	// before start-all or autostart launches anything
+	prerequisites := []string{}
+
 	mgr := NewManager(appConfigs)
+	mgr.runMarkers = *runMarkers
+	mgr.adhocJobs = *allowAdhocJobs
+	mgr.configPath = *configPath
+	mgr.profile = *profile
+	mgr.RecordStartupConfig(appConfigs)
+	sseEvents.SetWindow(*sseWindow)
+	hub.SetMaxClients(*maxEventClients)
+	mgr.memoryCeiling = *memoryCeilingMB * 1024 * 1024
+	mgr.crashArchive = s3ConfigFromEnv()
+	secretProvider = secretProviderFromEnv()
+	mgr.SetActions(actionConfigs)
+	mgr.SetPrerequisites(prerequisites)
+	mgr.SetJobTasks(jobTasks)
+	mgr.syncSchedules()
+	if *stateFile != "" {
+		mgr.stateFile = *stateFile
+		mgr.stateDirty = make(chan struct{}, 1)
+		mgr.adoptRuns()
+		go mgr.persistRuns()
+	}
 
 	// Start health checking in a goroutine
 	go mgr.RunHealthChecks(5 * time.Second)
+	go mgr.reloadOnSIGHUP()
+	go mgr.exitOnSignal()
+	if mgr.configPath != "" {
+		go mgr.watchConfig(*applyConfigChanges)
+	}
+	go mgr.watchBinaries()
+	go mgr.Autostart(*autostartWait)
 
 	http.HandleFunc("/api/apps", func(w http.ResponseWriter, r *http.Request) {
 		getAppsHandler(mgr, w, r)
 	})
 
+	http.HandleFunc("POST /api/apps/{action}", func(w http.ResponseWriter, r *http.Request) {
+		bulkHandler(mgr, w, r)
+	})
+
+	http.HandleFunc("POST /api/apps", func(w http.ResponseWriter, r *http.Request) {
+		createAppHandler(mgr, w, r)
+	})
+	http.HandleFunc("PUT /api/app/{name}", func(w http.ResponseWriter, r *http.Request) {
+		updateAppHandler(mgr, w, r)
+	})
+	http.HandleFunc("DELETE /api/app/{name}", func(w http.ResponseWriter, r *http.Request) {
+		deleteAppHandler(mgr, w, r)
+	})
 	http.HandleFunc("/api/app/", func(w http.ResponseWriter, r *http.Request) {
 		controlAppHandler(mgr, w, r)
 	})
 
+	http.HandleFunc("GET /api/health", func(w http.ResponseWriter, r *http.Request) {
+		getHealthSummaryHandler(mgr, w, r)
+	})
+	http.HandleFunc("GET /api/summary", func(w http.ResponseWriter, r *http.Request) {
+		getSummaryHandler(mgr, w, r)
+	})
+
+	http.HandleFunc("GET /api/config/validate", func(w http.ResponseWriter, r *http.Request) {
+		validateConfigHandler(mgr, w, r)
+	})
+	http.HandleFunc("GET /api/config/schema", configSchemaHandler)
+	http.HandleFunc("GET /api/config/diff", func(w http.ResponseWriter, r *http.Request) {
+		configDiffHandler(mgr, w, r)
+	})
+	http.HandleFunc("GET /api/config/history", func(w http.ResponseWriter, r *http.Request) {
+		configHistoryHandler(mgr, w, r)
+	})
+	http.HandleFunc("POST /api/config/rollback/{version}", func(w http.ResponseWriter, r *http.Request) {
+		rollbackHandler(mgr, w, r)
+	})
+	http.HandleFunc("GET /api/profile", func(w http.ResponseWriter, r *http.Request) {
+		getProfileHandler(mgr, w, r)
+	})
+	http.HandleFunc("PUT /api/profile", func(w http.ResponseWriter, r *http.Request) {
+		setProfileHandler(mgr, w, r)
+	})
+	http.HandleFunc("POST /api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		runJobHandler(mgr, w, r)
+	})
+	http.HandleFunc("GET /api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		listJobsHandler(mgr, w, r)
+	})
+	http.HandleFunc("GET /api/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		getJobHandler(mgr, w, r)
+	})
+	http.HandleFunc("DELETE /api/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		cancelJobHandler(mgr, w, r)
+	})
+	http.HandleFunc("GET /api/schedule", func(w http.ResponseWriter, r *http.Request) {
+		scheduleHandler(mgr, w, r)
+	})
+	http.HandleFunc("POST /api/reload", func(w http.ResponseWriter, r *http.Request) {
+		reloadHandler(mgr, w, r)
+	})
+	http.HandleFunc("GET /api/memory", func(w http.ResponseWriter, r *http.Request) {
+		getMemoryHandler(mgr, w, r)
+	})
+	http.HandleFunc("GET /api/app/{name}/health-history", func(w http.ResponseWriter, r *http.Request) {
+		getHealthHistoryHandler(mgr, w, r)
+	})
+
+	http.HandleFunc("GET /api/app/{name}/exits", func(w http.ResponseWriter, r *http.Request) {
+		getExitsHandler(mgr, w, r)
+	})
+
+	http.HandleFunc("POST /api/app/{name}/signal", func(w http.ResponseWriter, r *http.Request) {
+		signalAppHandler(mgr, w, r)
+	})
+	http.HandleFunc("POST /api/app/{name}/reset", func(w http.ResponseWriter, r *http.Request) {
+		resetAppHandler(mgr, w, r)
+	})
+	http.HandleFunc("POST /api/app/{name}/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		maintenanceHandler(mgr, w, r)
+	})
+	http.HandleFunc("DELETE /api/app/{name}/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		maintenanceHandler(mgr, w, r)
+	})
+	http.HandleFunc("POST /api/app/{name}/deploy", func(w http.ResponseWriter, r *http.Request) {
+		deployHandler(mgr, w, r)
+	})
+
+	http.HandleFunc("/api/app/{name}/proxy/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		proxyAppHandler(mgr, w, r)
+	})
+
+	http.HandleFunc("POST /api/action/{name}", func(w http.ResponseWriter, r *http.Request) {
+		runActionHandler(mgr, w, r)
+	})
+
+	http.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		statusPageHandler(mgr, w, r)
+	})
+
+	http.HandleFunc("GET /events", eventsHandler)
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		metricsHandler(mgr, w, r)
+	})
+
 	http.HandleFunc("/api/output/", func(w http.ResponseWriter, r *http.Request) {
 		getAppOutputHandler(mgr, w, r)
 	})
 	
-	port := 6978
+	_, portStr, err := net.SplitHostPort(*listen)
+	if err != nil {
+		log.Fatalf("Invalid -listen address %q: %v", *listen, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		log.Fatalf("Invalid -listen port %q: %v", portStr, err)
+	}
 	subscriptionURL := "http://0.0.0.0:6969/subscribe"
 	filterPattern := "PRIVMSG"
 	twitch_chat_subscriber.SendRequestWithCallbackAndRegex(subscriptionURL, handleMessage, filterPattern, port)
 	sse.Start()
 
-	portStr := ":6978"
-	log.Printf("App Manager listening on %d. Open http://localhost%s in your browser.", port, portStr)
-	if err := http.ListenAndServe(portStr, nil); err != nil {
+	log.Printf("App Manager listening on %s. Open http://localhost:%d in your browser.", *listen, port)
+	if err := http.ListenAndServe(*listen, nil); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
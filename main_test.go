@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestManager returns a manager for configs whose apps are stopped when
+// the test ends
+func newTestManager(t testing.TB, configs ...AppConfig) *Manager {
+	t.Helper()
+	m := NewManager(configs)
+	t.Cleanup(func() {
+		for _, name := range m.selectApps(func(app *AppState) bool { return app.Running }) {
+			m.StopApp(name)
+		}
+		m.Shutdown()
+	})
+	return m
+}
+
+func TestConcurrentStartAppLaunchesOnce(t *testing.T) {
+	// The dependency keeps web Starting for its settle period, so the second
+	// call always lands while the first is still in progress
+	m := newTestManager(t,
+		AppConfig{Name: "db", Path: "sleep", Args: []string{"60"}},
+		AppConfig{Name: "web", Path: "sleep", Args: []string{"60"}, DependsOn: []string{"db"}},
+	)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	start := make(chan struct{})
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = m.StartApp("web")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) != 1 {
+		t.Fatalf("StartApp errors = %v, want exactly one failure", errs)
+	}
+	if !strings.Contains(failed[0].Error(), "already starting") {
+		t.Errorf("failed StartApp error = %q, want it to say already starting", failed[0])
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if app := m.apps["web"]; !app.Running || app.RunCount != 1 {
+		t.Errorf("web Running = %v, RunCount = %d; want one running process", app.Running, app.RunCount)
+	}
+}
+
+// Run with -race: the handler reads the output buffer while the reader
+// goroutine is writing to it
+func TestGetAppOutputDuringHeavyOutput(t *testing.T) {
+	m := newTestManager(t, AppConfig{Name: "chatty", Path: "yes", Args: []string{"some output line"}})
+	if err := m.StartApp("chatty"); err != nil {
+		t.Fatal(err)
+	}
+	output := func() string {
+		rec := httptest.NewRecorder()
+		getAppOutputHandler(m, rec, httptest.NewRequest(http.MethodGet, "/api/output/chatty", nil))
+		return rec.Body.String()
+	}
+	for deadline := time.Now().Add(5 * time.Second); !strings.Contains(output(), "some output line"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("no output from the app: %q", output())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				rec := httptest.NewRecorder()
+				getAppOutputHandler(m, rec, httptest.NewRequest(http.MethodGet, "/api/output/chatty", nil))
+				if rec.Code != http.StatusOK {
+					t.Errorf("status = %d, want 200", rec.Code)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestStopAppRacingExitReportsOneExit(t *testing.T) {
+	m := newTestManager(t, AppConfig{Name: "brief", Path: "sleep", Args: []string{"0.05"}})
+	const runs = 20
+	for i := 1; i <= runs; i++ {
+		if err := m.StartApp("brief"); err != nil {
+			t.Fatalf("run %d: StartApp: %v", i, err)
+		}
+		time.Sleep(time.Duration(40+i%3*5) * time.Millisecond) // Around when it exits on its own
+		if err := m.StopApp("brief"); err != nil && !strings.Contains(err.Error(), "not running") {
+			t.Fatalf("run %d: StopApp: %v", i, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := m.waitExited(ctx, "brief")
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The exit is recorded once by whichever side saw it
+		for deadline := time.Now().Add(time.Second); ; {
+			m.mu.RLock()
+			exits, running := len(m.apps["brief"].exits), m.apps["brief"].Running
+			m.mu.RUnlock()
+			if exits == i && !running {
+				break
+			}
+			if exits > i || time.Now().After(deadline) {
+				t.Fatalf("run %d: %d exits recorded (running %v), want %d", i, exits, running, i)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
+
+// BenchmarkReadOutput compares read buffer sizes for an app writing output
+// faster than it can be read
+func BenchmarkReadOutput(b *testing.B) {
+	const total = 16 << 20
+	data := bytes.Repeat([]byte("2026-10-15T10:04:12Z INFO handled request in 1.2ms status=200\n"), 4096)
+	for _, size := range []int{4 << 10, 16 << 10, defaultReadBufferSize, 128 << 10} {
+		b.Run(fmt.Sprintf("%dKiB", size>>10), func(b *testing.B) {
+			m := newTestManager(b, AppConfig{Name: "chatty", ReadBufferSize: size})
+			m.mu.RLock()
+			app, cfg := m.apps["chatty"], m.apps["chatty"].Config
+			m.mu.RUnlock()
+			b.SetBytes(total)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r, w, err := os.Pipe()
+				if err != nil {
+					b.Fatal(err)
+				}
+				go func() {
+					for written := 0; written < total; written += len(data) {
+						w.Write(data)
+					}
+					w.Close()
+				}()
+				m.readOutput(app, cfg, r, nil)
+				r.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// SetMaintenance puts an app into maintenance or takes it out. An app in
+// maintenance keeps whatever state it is in: albert doesn't restart it after
+// it exits, and restart-all skips it. Starting and stopping it by hand still
+// works.
+func (m *Manager) SetMaintenance(appName string, on bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	app, ok := m.apps[appName]
+	if !ok {
+		return fmt.Errorf("app %s not found", appName)
+	}
+	if app.Maintenance == on {
+		return nil
+	}
+	app.Maintenance = on
+	if on {
+		app.cancelRestart()
+		appLogf(app.Config, "App %s is in maintenance", appName)
+	} else {
+		appLogf(app.Config, "App %s is out of maintenance", appName)
+	}
+	hub.Publish(EventAppState, app.stateEvent())
+	return nil
+}
+
+// maintenanceHandler puts an app into maintenance on POST and takes it out
+// on DELETE
+func maintenanceHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	on := r.Method == http.MethodPost
+	if err := mgr.SetMaintenance(appName, on); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "{\"status\": \"success\", \"message\": \"app %s maintenance %t\"}", appName, on)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/url"
+	"os/exec"
+	"strconv"
+
+	"github.com/hashicorp/mdns"
+)
+
+// mdnsService is the DNS-SD service type albert advertises apps under
+const mdnsService = "_albert._tcp"
+
+// mdnsPort returns the port to advertise for an app
+func mdnsPort(cfg AppConfig) (int, error) {
+	base, err := cfg.BaseURL()
+	if err != nil {
+		return 0, err
+	}
+	if p := base.Port(); p != "" {
+		return strconv.Atoi(p)
+	}
+	if base.Scheme == "https" {
+		return 443, nil
+	}
+	return 80, nil
+}
+
+// advertiseApp announces a running app over mDNS. It runs in the background
+// since building the service record may block on host lookups, and only
+// installs the server if the same run is still current.
+func (m *Manager) advertiseApp(app *AppState, cfg AppConfig, cmd *exec.Cmd) {
+	port, err := mdnsPort(cfg)
+	if err != nil {
+		appLogf(cfg, "Not advertising %s over mDNS: %v", cfg.Name, err)
+		return
+	}
+	base, _ := cfg.BaseURL()
+	service, err := mdns.NewMDNSService(cfg.Name, mdnsService, "", "", port, nil, []string{
+		"app=" + cfg.Name,
+		"url=" + (&url.URL{Scheme: base.Scheme, Host: base.Host}).String(),
+	})
+	if err != nil {
+		appLogf(cfg, "Failed to build mDNS record for %s: %v", cfg.Name, err)
+		return
+	}
+	server, err := mdns.NewServer(&mdns.Config{Zone: service})
+	if err != nil {
+		appLogf(cfg, "Failed to advertise %s over mDNS: %v", cfg.Name, err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if app.Cmd != cmd || !app.Running {
+		server.Shutdown() // The run we were advertising is already over
+		return
+	}
+	app.withdrawMDNS()
+	app.mdnsServer = server
+	appLogf(cfg, "Advertising %s over mDNS as %s.%s.local.", cfg.Name, cfg.Name, mdnsService)
+}
+
+// withdrawMDNS stops advertising the app. Must be called with m.mu held.
+func (app *AppState) withdrawMDNS() {
+	if app.mdnsServer != nil {
+		app.mdnsServer.Shutdown()
+		app.mdnsServer = nil
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MemoryUsage is the aggregate resident memory of all running apps
+type MemoryUsage struct {
+	RSSBytes     uint64 `json:"rss_bytes"`
+	CeilingBytes uint64 `json:"ceiling_bytes"` // 0 when no ceiling is configured
+}
+
+// processRSS returns the resident set size of a process in bytes
+func processRSS(pid int) (uint64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected statm format for pid %d", pid)
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected statm format for pid %d: %w", pid, err)
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
+
+// sampleMemory records the RSS of every running app and returns the total
+func (m *Manager) sampleMemory() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var total uint64
+	for _, app := range m.apps {
+		app.RSSBytes = 0
+		if !app.Running || app.Cmd == nil || app.Cmd.Process == nil {
+			continue
+		}
+		rss, err := processRSS(app.Cmd.Process.Pid)
+		if err != nil {
+			continue // Most likely exited between the check and the read
+		}
+		app.RSSBytes = rss
+		total += rss
+	}
+	m.memoryUsed = total
+	return total
+}
+
+// evictionCandidate returns the running app to stop first under memory
+// pressure: the lowest Priority, then the largest RSS
+func (m *Manager) evictionCandidate() (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var victim *AppState
+	for _, app := range m.apps {
+		if !app.Running || app.RSSBytes == 0 {
+			continue
+		}
+		if victim == nil {
+			victim = app
+			continue
+		}
+		w, vw := outputWeight(app.Config), outputWeight(victim.Config)
+		if w < vw || (w == vw && app.RSSBytes > victim.RSSBytes) {
+			victim = app
+		}
+	}
+	if victim == nil {
+		return "", false
+	}
+	return victim.Config.Name, true
+}
+
+// enforceMemoryCeiling samples app memory and, if the total is above the
+// ceiling, stops one app. Running on every health tick gives the system time
+// to settle before deciding whether another eviction is needed.
+func (m *Manager) enforceMemoryCeiling() {
+	total := m.sampleMemory()
+	if m.memoryCeiling == 0 || total <= m.memoryCeiling {
+		return
+	}
+	name, ok := m.evictionCandidate()
+	if !ok {
+		return
+	}
+	cfg := m.appConfig(name)
+	if err := m.StopApp(name); err != nil {
+		appLogf(cfg, "Failed to evict %s under memory pressure: %v", name, err)
+		return
+	}
+	appLogf(cfg, "Evicted %s: apps are using %d MB, above the %d MB ceiling", name, total/(1024*1024), m.memoryCeiling/(1024*1024))
+}
+
+// getMemoryHandler returns aggregate app memory usage and the ceiling
+func getMemoryHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	mgr.mu.RLock()
+	usage := MemoryUsage{RSSBytes: mgr.memoryUsed, CeilingBytes: mgr.memoryCeiling}
+	mgr.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		log.Printf("Error encoding memory usage: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// reservedLabelNames are the label names albert's own metrics use. App
+// labels with these names are exported with a "label_" prefix instead.
+var reservedLabelNames = map[string]bool{"app": true, "reason": true}
+
+// promLabels renders the app name plus its configured labels in Prometheus
+// exposition syntax, followed by any extra name/value pairs. Label names are
+// sanitized, and prefixed if they're in reservedLabelNames.
+func promLabels(cfg AppConfig, extra ...string) string {
+	pairs := []string{`app="` + labelValueEscaper.Replace(cfg.Name) + `"`}
+	for _, k := range sortedLabelKeys(cfg.Labels) {
+		name := invalidLabelChars.ReplaceAllString(k, "_")
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			continue
+		}
+		if reservedLabelNames[name] {
+			name = "label_" + name
+		}
+		pairs = append(pairs, name+`="`+labelValueEscaper.Replace(cfg.Labels[k])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+labelValueEscaper.Replace(extra[i+1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// metric is a single gauge family written to /metrics
+type metric struct {
+	name, help string
+	value      func(app *AppState) float64
+}
+
+var appMetrics = []metric{
+	{"albert_app_running", "Whether the app process is running.", func(app *AppState) float64 {
+		return boolFloat(app.Running)
+	}},
+	{"albert_app_healthy", "Whether the last health check reported Healthy.", func(app *AppState) float64 {
+		return boolFloat(app.HealthStatus == "Healthy")
+	}},
+	{"albert_app_health_latency_seconds", "Latency of the last health check.", func(app *AppState) float64 {
+		return (time.Duration(app.HealthLatencyMS) * time.Millisecond).Seconds()
+	}},
+	{"albert_app_quarantined", "Whether the app is quarantined for crash-looping.", func(app *AppState) float64 {
+		return boolFloat(app.Quarantined)
+	}},
+	{"albert_app_memory_rss_bytes", "Resident memory of the app process as of the last sample.", func(app *AppState) float64 {
+		return float64(app.RSSBytes)
+	}},
+	{"albert_app_output_buffer_bytes", "Bytes of output currently buffered.", func(app *AppState) float64 {
+		return float64(app.OutputBuffer.Len())
+	}},
+}
+
+// boolFloat converts a bool to a 0/1 gauge value
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// writeMetrics writes all app metrics in the Prometheus text format.
+// Must be called with m.mu held.
+func (m *Manager) writeMetrics(w io.Writer) {
+	names := make([]string, 0, len(m.apps))
+	for name := range m.apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, mt := range appMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", mt.name, mt.help, mt.name)
+		for _, name := range names {
+			app := m.apps[name]
+			fmt.Fprintf(w, "%s%s %g\n", mt.name, promLabels(app.Config), mt.value(app))
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP albert_memory_rss_bytes Resident memory of all running apps together.\n# TYPE albert_memory_rss_bytes gauge\nalbert_memory_rss_bytes %d\n", m.memoryUsed)
+	fmt.Fprintf(w, "# HELP albert_memory_ceiling_bytes Total app memory above which apps are evicted, 0 if unset.\n# TYPE albert_memory_ceiling_bytes gauge\nalbert_memory_ceiling_bytes %d\n", m.memoryCeiling)
+
+	clients, maxClients := hub.Stats()
+	fmt.Fprintf(w, "# HELP albert_event_clients Connected /events clients.\n# TYPE albert_event_clients gauge\nalbert_event_clients %d\n", clients)
+	fmt.Fprintf(w, "# HELP albert_event_clients_max Cap on /events clients, 0 if unlimited.\n# TYPE albert_event_clients_max gauge\nalbert_event_clients_max %d\n", maxClients)
+
+	fmt.Fprintf(w, "# HELP albert_app_restarts_total Restarts performed by albert, by reason.\n# TYPE albert_app_restarts_total counter\n")
+	for _, name := range names {
+		app := m.apps[name]
+		for _, reason := range sortedLabelKeys(app.Restarts) {
+			fmt.Fprintf(w, "albert_app_restarts_total%s %d\n", promLabels(app.Config, "reason", reason), app.Restarts[reason])
+		}
+	}
+}
+
+// metricsHandler serves app metrics for Prometheus to scrape
+func metricsHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	mgr.mu.RLock()
+	defer mgr.mu.RUnlock()
+	mgr.writeMetrics(w)
+}
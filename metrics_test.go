@@ -0,0 +1,12 @@
+package main
+
+import "testing"
+
+func TestPromLabelsPrefixesReservedNames(t *testing.T) {
+	cfg := AppConfig{Name: "web", Labels: map[string]string{"reason": "mine", "app": "shop", "team": "core"}}
+	got := promLabels(cfg, "reason", "crash")
+	want := `{app="web",label_app="shop",label_reason="mine",team="core",reason="crash"}`
+	if got != want {
+		t.Errorf("promLabels = %s, want %s", got, want)
+	}
+}
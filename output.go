@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Output retention defaults for the lines and time strategies
+const (
+	defaultBufferLines  = 500
+	defaultBufferWindow = 10 * time.Minute
+)
+
+// OutputBuffer retains an app's recent output according to its
+// BufferStrategy. Every strategy also stays within the app's share of the
+// output budget. Implementations are not safe for concurrent use; callers
+// hold m.mu.
+type OutputBuffer interface {
+	io.Writer      // Never fails
+	Bytes() []byte // A copy of the retained output, oldest first
+	Len() int
+	Reset()
+	SetLimit(limit int) // Caps retained output at limit bytes
+}
+
+// newOutputBuffer returns the buffer for an app's BufferStrategy
+func newOutputBuffer(cfg AppConfig) (OutputBuffer, error) {
+	switch cfg.BufferStrategy {
+	case "", "bytes":
+		return &byteBuffer{}, nil
+	case "lines":
+		maxLines := cfg.BufferLines
+		if maxLines <= 0 {
+			maxLines = defaultBufferLines
+		}
+		return &lineBuffer{maxLines: maxLines}, nil
+	case "time":
+		window := time.Duration(cfg.BufferWindow)
+		if window <= 0 {
+			window = defaultBufferWindow
+		}
+		return &timeBuffer{window: window}, nil
+	default:
+		return nil, fmt.Errorf("app %s has unknown buffer strategy %q", cfg.Name, cfg.BufferStrategy)
+	}
+}
+
+// byteBuffer keeps the most recent output up to the byte limit
+type byteBuffer struct {
+	buf   []byte
+	limit int
+}
+
+func (b *byteBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	b.trim()
+	return len(p), nil
+}
+
+// trim drops the oldest output once the buffer grows past the limit,
+// keeping the most recent half so we don't copy on every write
+func (b *byteBuffer) trim() {
+	if len(b.buf) <= b.limit {
+		return
+	}
+	keep := b.limit / 2
+	b.buf = append(make([]byte, 0, b.limit), b.buf[len(b.buf)-keep:]...)
+}
+
+func (b *byteBuffer) Bytes() []byte { return append([]byte(nil), b.buf...) }
+func (b *byteBuffer) Len() int      { return len(b.buf) }
+func (b *byteBuffer) Reset()        { b.buf = nil }
+
+func (b *byteBuffer) SetLimit(limit int) {
+	b.limit = limit
+	b.trim()
+}
+
+// lineBuffer keeps the last maxLines lines, dropping whole lines. A trailing
+// partial line is kept and completed by later writes.
+type lineBuffer struct {
+	lines    []string
+	size     int
+	maxLines int
+	limit    int
+}
+
+func (b *lineBuffer) Write(p []byte) (int, error) {
+	s := string(p)
+	for len(s) > 0 {
+		part := s
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			part = s[:i+1]
+		}
+		s = s[len(part):]
+		if n := len(b.lines); n > 0 && !strings.HasSuffix(b.lines[n-1], "\n") {
+			b.lines[n-1] += part
+		} else {
+			b.lines = append(b.lines, part)
+		}
+		b.size += len(part)
+	}
+	b.trim()
+	return len(p), nil
+}
+
+// trim drops the oldest lines past maxLines or the byte limit
+func (b *lineBuffer) trim() {
+	drop := 0
+	for drop < len(b.lines) && (len(b.lines)-drop > b.maxLines || b.size > b.limit) {
+		b.size -= len(b.lines[drop])
+		drop++
+	}
+	b.lines = b.lines[drop:]
+}
+
+func (b *lineBuffer) Bytes() []byte { return []byte(strings.Join(b.lines, "")) }
+func (b *lineBuffer) Len() int      { return b.size }
+
+func (b *lineBuffer) Reset() {
+	b.lines = nil
+	b.size = 0
+}
+
+func (b *lineBuffer) SetLimit(limit int) {
+	b.limit = limit
+	b.trim()
+}
+
+// timedChunk is output read at one time
+type timedChunk struct {
+	at   time.Time
+	data []byte
+}
+
+// timeBuffer keeps output read within the last window
+type timeBuffer struct {
+	chunks []timedChunk
+	size   int
+	window time.Duration
+	limit  int
+}
+
+func (b *timeBuffer) Write(p []byte) (int, error) {
+	b.chunks = append(b.chunks, timedChunk{at: time.Now(), data: append([]byte(nil), p...)})
+	b.size += len(p)
+	b.trim()
+	return len(p), nil
+}
+
+// trim drops chunks older than the window or past the byte limit
+func (b *timeBuffer) trim() {
+	cutoff := time.Now().Add(-b.window)
+	drop := 0
+	for drop < len(b.chunks) && (b.chunks[drop].at.Before(cutoff) || b.size > b.limit) {
+		b.size -= len(b.chunks[drop].data)
+		drop++
+	}
+	b.chunks = b.chunks[drop:]
+}
+
+// live returns the chunks still inside the window. Reads don't trim, since
+// they may happen under a read lock.
+func (b *timeBuffer) live() []timedChunk {
+	cutoff := time.Now().Add(-b.window)
+	for i, c := range b.chunks {
+		if !c.at.Before(cutoff) {
+			return b.chunks[i:]
+		}
+	}
+	return nil
+}
+
+func (b *timeBuffer) Bytes() []byte {
+	var out []byte
+	for _, c := range b.live() {
+		out = append(out, c.data...)
+	}
+	return out
+}
+
+func (b *timeBuffer) Len() int {
+	n := 0
+	for _, c := range b.live() {
+		n += len(c.data)
+	}
+	return n
+}
+
+func (b *timeBuffer) Reset() {
+	b.chunks = nil
+	b.size = 0
+}
+
+func (b *timeBuffer) SetLimit(limit int) {
+	b.limit = limit
+	b.trim()
+}
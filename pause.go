@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// healthPaused is the HealthStatus of a paused app, which isn't health
+// checked until it is resumed
+const healthPaused = "Paused"
+
+// PauseApp suspends a running app's process group with SIGSTOP. The app
+// keeps its memory and stays Running; ResumeApp continues it.
+func (m *Manager) PauseApp(appName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	app, err := m.signalableApp(appName)
+	if err != nil {
+		return err
+	}
+	if app.Paused {
+		return fmt.Errorf("app %s is already paused", appName)
+	}
+	if err := signalApp(app.Cmd.Process, syscall.SIGSTOP); err != nil {
+		return fmt.Errorf("failed to pause app %s: %w", appName, err)
+	}
+	app.Paused = true
+	app.HealthStatus = healthPaused
+	hub.Publish(EventAppState, app.stateEvent())
+	appLogf(app.Config, "Paused app: %s", appName)
+	return nil
+}
+
+// ResumeApp continues a paused app with SIGCONT
+func (m *Manager) ResumeApp(appName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	app, err := m.signalableApp(appName)
+	if err != nil {
+		return err
+	}
+	if !app.Paused {
+		return fmt.Errorf("app %s is not paused", appName)
+	}
+	if err := signalApp(app.Cmd.Process, syscall.SIGCONT); err != nil {
+		return fmt.Errorf("failed to resume app %s: %w", appName, err)
+	}
+	app.Paused = false
+	app.HealthStatus = "Unknown" // Until the next health check
+	hub.Publish(EventAppState, app.stateEvent())
+	appLogf(app.Config, "Resumed app: %s", appName)
+	return nil
+}
+
+// signalableApp returns a running app whose process can be signaled. Must
+// be called with m.mu held.
+func (m *Manager) signalableApp(appName string) (*AppState, error) {
+	app, ok := m.apps[appName]
+	if !ok {
+		return nil, fmt.Errorf("app %s not found", appName)
+	}
+	if !app.Running || app.Stopping || app.Cmd == nil || app.Cmd.Process == nil {
+		return nil, fmt.Errorf("app %s is not running", appName)
+	}
+	return app, nil
+}
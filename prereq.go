@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// prerequisitePollInterval is how often unreachable prerequisites are retried
+const prerequisitePollInterval = 2 * time.Second
+
+// SetPrerequisites replaces the external endpoints that must be reachable
+// before apps are started in bulk. Each is an http(s):// URL that must answer
+// 2xx, or a tcp://host:port address that must accept connections.
+func (m *Manager) SetPrerequisites(endpoints []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prerequisites = append([]string(nil), endpoints...)
+}
+
+// checkPrerequisite probes a single prerequisite endpoint once
+func checkPrerequisite(ctx context.Context, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	switch u.Scheme {
+	case "tcp":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+}
+
+// waitPrerequisites blocks until every prerequisite is reachable or ctx is
+// done, in which case the error names the prerequisite still blocking
+func (m *Manager) waitPrerequisites(ctx context.Context) error {
+	m.mu.RLock()
+	pending := append([]string(nil), m.prerequisites...)
+	m.mu.RUnlock()
+
+	for len(pending) > 0 {
+		var lastErr error
+		for len(pending) > 0 {
+			if lastErr = checkPrerequisite(ctx, pending[0]); lastErr != nil {
+				break
+			}
+			pending = pending[1:]
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		log.Printf("Waiting for prerequisite %s: %v", pending[0], lastErr)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("prerequisite %s is not reachable: %v", pending[0], lastErr)
+		case <-time.After(prerequisitePollInterval):
+		}
+	}
+	return nil
+}
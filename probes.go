@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// defaultReadyInterval is how often a starting app's readiness is probed
+const defaultReadyInterval = time.Second
+
+// readyPollInterval is how often waitReady looks at an app's readiness
+const readyPollInterval = 250 * time.Millisecond
+
+// HealthStatus of an app that hasn't passed its readiness probe yet, and of
+// one that was killed for not passing it within StartTimeout
+const (
+	healthStarting     = "Starting"
+	healthStartTimeout = "StartTimeout"
+)
+
+// readyURL returns the URL of the app's readiness probe: ReadyURL, or
+// HealthURL when that isn't set
+func (cfg AppConfig) readyURL() string {
+	if cfg.ReadyURL != "" {
+		return cfg.ReadyURL
+	}
+	return cfg.HealthURL
+}
+
+// liveURL returns the URL of the app's liveness probe: LiveURL, or HealthURL
+// when that isn't set
+func (cfg AppConfig) liveURL() string {
+	if cfg.LiveURL != "" {
+		return cfg.LiveURL
+	}
+	return cfg.HealthURL
+}
+
+// probeFor returns cfg set up to probe url with probeHealth
+func (cfg AppConfig) probeFor(url string) AppConfig {
+	cfg.HealthURL = url
+	return cfg
+}
+
+// gateReady probes a freshly started run's readiness every ReadyInterval
+// until it passes, then marks the app Ready. It gives up once cmd is no
+// longer the app's process, and kills the run if it is still not ready after
+// StartTimeout; the exit is then reported as a failed start.
+func (m *Manager) gateReady(app *AppState, cfg AppConfig, cmd *exec.Cmd) {
+	probe := cfg.probeFor(cfg.readyURL())
+	ticker := time.NewTicker(durationOr(cfg.ReadyInterval, defaultReadyInterval))
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		result, err := probeHealth(probe)
+		latency := time.Since(start)
+
+		m.mu.Lock()
+		if app.Cmd != cmd {
+			m.mu.Unlock()
+			return // Exited or stopped before it got ready
+		}
+		if err == nil && passesHealth(result.Status) {
+			app.Ready = true
+			app.HealthStatus = result.Status
+			app.HealthCode = result.Code
+			app.HealthDetail = result.Detail
+			app.HealthLatencyMS = latency.Milliseconds()
+			app.HealthLastCheck = time.Now()
+			hub.Publish(EventAppState, app.stateEvent())
+			m.mu.Unlock()
+			appLogf(cfg, "App %s is ready after %s", cfg.Name, time.Since(app.StartedAt).Round(time.Millisecond))
+			return
+		}
+		status := result.Status
+		if err != nil {
+			status = fmt.Sprintf("Error: %v", err)
+		}
+		if timeout := time.Duration(cfg.StartTimeout); timeout > 0 && time.Since(app.StartedAt) >= timeout {
+			app.startTimedOut = true
+			m.mu.Unlock()
+			appLogf(cfg, "App %s is not ready %s after starting (readiness probe: %s), killing it", cfg.Name, timeout, status)
+			if err := killApp(cmd.Process); err != nil && !errors.Is(err, os.ErrProcessDone) {
+				appLogf(cfg, "Failed to kill %s: %v", cfg.Name, err)
+			}
+			return
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// isReady reports whether the app's current run has passed its readiness
+// probe, or for apps without one, has been up for deploySettle. Must be
+// called with m.mu held.
+func (app *AppState) isReady(now time.Time) bool {
+	if !app.Running {
+		return false
+	}
+	if app.Config.readyURL() != "" {
+		return app.Ready
+	}
+	return now.Sub(app.StartedAt) >= deploySettle
+}
+
+// liveCheckDue reports whether the health sweep should probe a running app's
+// liveness now: not before it is ready, and no more often than LiveInterval.
+// Must be called with m.mu held.
+func (app *AppState) liveCheckDue(now time.Time) bool {
+	if !app.Ready {
+		return false // Still behind the readiness gate
+	}
+	interval := time.Duration(app.Config.LiveInterval)
+	return interval <= 0 || now.Sub(app.HealthLastCheck) >= interval
+}
+
+// noteLiveness counts consecutive failed liveness checks and reports whether
+// the app has now failed LiveFailures in a row and should be restarted.
+// Called after each health check. Must be called with m.mu held.
+func (app *AppState) noteLiveness() bool {
+	if app.Config.LiveFailures <= 0 || app.Config.liveURL() == "" {
+		return false
+	}
+	if passesHealth(app.HealthStatus) {
+		app.liveFailures = 0
+		return false
+	}
+	app.liveFailures++
+	if app.liveFailures < app.Config.LiveFailures {
+		return false
+	}
+	app.liveFailures = 0
+	return true
+}
+
+// restartUnlive restarts an app that failed too many liveness checks
+func (m *Manager) restartUnlive(appName string) {
+	cfg := m.appConfig(appName)
+	appLogf(cfg, "App %s failed %d liveness checks in a row, restarting it", appName, cfg.LiveFailures)
+	if err := m.RestartApp(appName, RestartLiveness); err != nil {
+		appLogf(cfg, "Failed to restart %s after failed liveness checks: %v", appName, err)
+	}
+}
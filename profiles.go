@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// Profile adjusts the app list for one way of running albert, e.g. "dev" or
+// "streaming"
+type Profile struct {
+	Exclude []string                   `json:"exclude,omitempty"` // Apps left out entirely
+	Apps    map[string]json.RawMessage `json:"apps,omitempty"`    // Settings to override, by app name
+}
+
+// applyProfile returns the apps with the named profile applied. Overrides
+// only replace the settings they list; env entries are added to the app's
+// own. An empty name returns the apps unchanged.
+func (fc FileConfig) applyProfile(name string) ([]AppConfig, error) {
+	apps := fc.AllApps()
+	if name == "" {
+		return apps, nil
+	}
+	profile, ok := fc.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+
+	excluded := make(map[string]bool, len(profile.Exclude))
+	for _, appName := range profile.Exclude {
+		excluded[appName] = true
+	}
+	overridden := make(map[string]bool, len(profile.Apps))
+	kept := apps[:0]
+	for _, cfg := range apps {
+		if excluded[cfg.Name] {
+			continue
+		}
+		if override, ok := profile.Apps[cfg.Name]; ok {
+			// Decode onto a deep copy so the override can't write into slices
+			// and maps shared with the file's own definition
+			appName := cfg.Name
+			base, err := json.Marshal(cfg)
+			if err != nil {
+				return nil, err
+			}
+			cfg = AppConfig{}
+			if err := json.Unmarshal(base, &cfg); err != nil {
+				return nil, err
+			}
+			dec := json.NewDecoder(bytes.NewReader(override))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&cfg); err != nil {
+				return nil, fmt.Errorf("profile %s: invalid override for %s: %w", name, appName, err)
+			}
+			if cfg.Name != appName {
+				return nil, fmt.Errorf("profile %s: override for %s can't rename it", name, appName)
+			}
+			overridden[cfg.Name] = true
+		}
+		kept = append(kept, cfg)
+	}
+	for appName := range profile.Apps {
+		if !overridden[appName] && !excluded[appName] {
+			return nil, fmt.Errorf("profile %s overrides unknown app %s", name, appName)
+		}
+	}
+	return kept, nil
+}
+
+// profileNames returns the profiles defined in a config file, sorted
+func (fc FileConfig) profileNames() []string {
+	names := make([]string, 0, len(fc.Profiles))
+	for name := range fc.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the active profile; "" when none is active
+func (m *Manager) Profile() string {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	return m.profile
+}
+
+// SetProfile switches to another profile and reloads the config: apps the
+// profile excludes are stopped and removed, and apps it brings back are
+// added. If the new profile can't be loaded the current one stays active.
+func (m *Manager) SetProfile(name string, restartChanged bool) (ReloadResult, error) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	previous := m.profile
+	m.profile = name
+	result, err := m.reloadLocked(restartChanged, ConfigSourceProfile)
+	if err != nil {
+		m.profile = previous
+		return result, err
+	}
+	log.Printf("Switched profile from %q to %q", previous, name)
+	return result, nil
+}
+
+// profileResponse is returned by GET /api/profile
+type profileResponse struct {
+	Active    string   `json:"active"`
+	Available []string `json:"available"`
+}
+
+// getProfileHandler reports the active profile and the ones the config file
+// defines
+func getProfileHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	resp := profileResponse{Active: mgr.Profile(), Available: []string{}}
+	if mgr.configPath != "" {
+		if fc, err := parseConfigFile(mgr.configPath); err == nil {
+			resp.Available = fc.profileNames()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding profile: %v", err)
+	}
+}
+
+// setProfileHandler switches profile, taking {"profile": "name"} with an
+// empty name for none. With ?restart=true running apps whose config changed
+// are restarted.
+func setProfileHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Profile string `json:"profile"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	result, err := mgr.SetProfile(req.Profile, r.URL.Query().Get("restart") == "true")
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		result.Error = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding profile switch result: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+)
+
+// BaseURL returns the root URL the app serves HTTP on. It uses Port when set,
+// otherwise falls back to the scheme and host of HealthURL.
+func (cfg AppConfig) BaseURL() (*url.URL, error) {
+	if cfg.Port > 0 {
+		return &url.URL{Scheme: "http", Host: fmt.Sprintf("127.0.0.1:%d", cfg.Port)}, nil
+	}
+	if cfg.HealthURL == "" {
+		return nil, fmt.Errorf("app %s has no port or health URL", cfg.Name)
+	}
+	u, err := url.Parse(cfg.HealthURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health URL for %s: %w", cfg.Name, err)
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host}, nil
+}
+
+// proxyAppHandler forwards /api/app/{name}/proxy/{path...} to the app itself
+func proxyAppHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	mgr.mu.RLock()
+	app, ok := mgr.apps[appName]
+	mgr.mu.RUnlock()
+	if !ok {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+
+	// Apps with an IdleTimeout are started on demand
+	if err := mgr.ensureStarted(r.Context(), appName); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	mgr.mu.Lock()
+	app.LastRequest = time.Now()
+	cfg := app.Config
+	mgr.mu.Unlock()
+
+	target, err := cfg.BaseURL()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	path := "/" + r.PathValue("path")
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.URL.Path = path
+			pr.Out.URL.RawPath = ""
+			pr.SetXForwarded()
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+)
+
+// ReloadResult describes what a config reload changed
+type ReloadResult struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Changed   []string `json:"changed"`
+	Restarted []string `json:"restarted"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// newReloadResult returns an empty result whose lists encode as []
+func newReloadResult() ReloadResult {
+	return ReloadResult{Added: []string{}, Removed: []string{}, Changed: []string{}, Restarted: []string{}}
+}
+
+// loadConfigs reads the full app list the way albert builds it at startup:
+// the config file with the active profile applied, overlaid with APP_<n>_*
+// environment variables, with ${VAR} references expanded. Must be called
+// with m.reloadMu held.
+func (m *Manager) loadConfigs() ([]AppConfig, error) {
+	if m.configPath == "" {
+		return nil, fmt.Errorf("no config file to reload; start albert with --config")
+	}
+	fc, err := loadConfigFile(m.configPath)
+	if err != nil {
+		return nil, err
+	}
+	envConfigs, err := configsFromEnv(os.Environ())
+	if err != nil {
+		return nil, fmt.Errorf("invalid app configuration in environment: %w", err)
+	}
+	apps, err := fc.applyProfile(m.profile)
+	if err != nil {
+		return nil, err
+	}
+	configs, err := expandConfigs(mergeConfigs(apps, envConfigs))
+	if err != nil {
+		return nil, err
+	}
+	if err := validateConfigs(configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// Reload re-reads the config file and applies it: new apps are added,
+// removed apps are stopped and dropped, and changed apps get their new
+// config. Running apps that changed are restarted if restartChanged is set
+// or they have RestartOnConfigChange; the rest pick up the change on their
+// next start. The new config is fully validated first, so a broken file
+// leaves everything as it was.
+func (m *Manager) Reload(restartChanged bool) (ReloadResult, error) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	return m.reloadLocked(restartChanged, ConfigSourceReload)
+}
+
+// reloadLocked does the work of Reload, recording the new config in the
+// history under source if anything changed. Must be called with m.reloadMu
+// held.
+func (m *Manager) reloadLocked(restartChanged bool, source string) (ReloadResult, error) {
+	configs, err := m.loadConfigs()
+	if err != nil {
+		log.Printf("Config reload failed, keeping the current config: %v", err)
+		return newReloadResult(), err
+	}
+	result := m.applyConfigsLocked(configs, restartChanged)
+	log.Printf("Reloaded config from %s: %d added, %d removed, %d changed, %d restarted",
+		m.configPath, len(result.Added), len(result.Removed), len(result.Changed), len(result.Restarted))
+	if len(result.Added)+len(result.Removed)+len(result.Changed) > 0 {
+		m.recordConfigVersion(source, configs)
+	}
+	return result, nil
+}
+
+// applyConfigsLocked makes configs the running config: new apps are added,
+// missing ones stopped and dropped, and changed ones updated and restarted
+// as Reload describes. Must be called with m.reloadMu held.
+func (m *Manager) applyConfigsLocked(configs []AppConfig, restartChanged bool) ReloadResult {
+	result := newReloadResult()
+	wanted := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		wanted[cfg.Name] = true
+	}
+	removed := m.selectApps(func(app *AppState) bool { return !wanted[app.Config.Name] })
+	for _, name := range removed {
+		if err := m.StopApp(name); err == nil {
+			appLogf(m.appConfig(name), "Stopped %s, which was removed from the config", name)
+		}
+	}
+
+	var restart []string
+	m.mu.Lock()
+	for _, name := range removed {
+		if app, ok := m.apps[name]; ok {
+			app.withdrawMDNS()
+			app.cancelRestart()
+			delete(m.apps, name)
+		}
+		healthClients.Forget(name)
+	}
+	result.Removed = append(result.Removed, removed...)
+	for _, cfg := range configs {
+		app, ok := m.apps[cfg.Name]
+		if !ok {
+			m.apps[cfg.Name] = newAppState(cfg)
+			result.Added = append(result.Added, cfg.Name)
+			continue
+		}
+		if app.Config.alternate != nil && cfg.alternate != nil && app.Config.Port == cfg.DeployPort && app.Config.DeployPort == cfg.Port {
+			cfg = cfg.swapPorts() // Still on the port a blue/green deploy moved it to
+		}
+		if reflect.DeepEqual(app.Config, cfg) {
+			continue
+		}
+		if !sameBufferSettings(app.Config, cfg) {
+			if output, err := newOutputBuffer(cfg); err == nil {
+				output.SetLimit(app.OutputLimit) // Before copying, or a zero limit drops it all
+				output.Write(app.OutputBuffer.Bytes())
+				app.OutputBuffer = output
+			} else {
+				appLogf(cfg, "%v, keeping the current output buffer", err)
+			}
+		}
+		app.Config = cfg
+		healthClients.Forget(cfg.Name) // Picks up a changed CA bundle too
+		result.Changed = append(result.Changed, cfg.Name)
+		if app.Running && (restartChanged || cfg.RestartOnConfigChange) {
+			restart = append(restart, cfg.Name)
+		}
+	}
+	m.rebalanceOutputBuffers()
+	m.mu.Unlock()
+	m.syncSchedules()
+
+	for _, name := range restart {
+		if err := m.RestartApp(name, RestartConfigChange); err != nil {
+			appLogf(m.appConfig(name), "Failed to restart %s after config change: %v", name, err)
+			continue
+		}
+		result.Restarted = append(result.Restarted, name)
+	}
+	return result
+}
+
+// sameBufferSettings reports whether two configs retain output the same way
+func sameBufferSettings(a, b AppConfig) bool {
+	return a.BufferStrategy == b.BufferStrategy && a.BufferLines == b.BufferLines && a.BufferWindow == b.BufferWindow
+}
+
+// reloadOnSIGHUP reloads the config each time albert receives SIGHUP.
+// Changed apps are only restarted if they have RestartOnConfigChange.
+func (m *Manager) reloadOnSIGHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-hup:
+			log.Printf("SIGHUP received, reloading config")
+			m.Reload(false)
+		}
+	}
+}
+
+// reloadHandler reloads the config file. With ?restart=true every running
+// app whose config changed is restarted.
+func reloadHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	result, err := mgr.Reload(r.URL.Query().Get("restart") == "true")
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		result.Error = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding reload result: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReloadKeepsOutputWhenBufferStrategyChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "albert.yaml")
+	write := func(config string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("apps:\n  - name: web\n    path: sleep\n")
+
+	m := NewManager([]AppConfig{{Name: "web", Path: "sleep"}})
+	m.configPath = path
+	m.mu.Lock()
+	m.apps["web"].OutputBuffer.Write([]byte("earlier output\n"))
+	m.mu.Unlock()
+
+	write("apps:\n  - name: web\n    path: sleep\n    buffer_strategy: lines\n")
+	result, err := m.Reload(false)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(result.Changed) != 1 || result.Changed[0] != "web" {
+		t.Fatalf("Changed = %v, want [web]", result.Changed)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.apps["web"].OutputBuffer.(*lineBuffer); !ok {
+		t.Fatalf("OutputBuffer is %T, want *lineBuffer", m.apps["web"].OutputBuffer)
+	}
+	if out := string(m.apps["web"].OutputBuffer.Bytes()); !strings.Contains(out, "earlier output") {
+		t.Errorf("output after reload = %q, want the earlier output kept", out)
+	}
+}
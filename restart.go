@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Restart policies: what albert does when an app's process exits on its own
+const (
+	RestartPolicyNever     = "never" // Default: the app is left stopped
+	RestartPolicyOnFailure = "on-failure"
+	RestartPolicyAlways    = "always"
+)
+
+// Defaults for the restart policy settings
+const (
+	defaultRestartDelay      = time.Second // After a clean exit under "always"
+	defaultRestartBackoff    = time.Second // Before the first restart after a crash
+	defaultRestartBackoffMax = time.Minute
+	defaultStableAfter       = 30 * time.Second
+)
+
+// restartPolicy returns the app's restart policy, or an error for an
+// unknown one
+func (cfg AppConfig) restartPolicy() (string, error) {
+	switch cfg.Restart {
+	case "", RestartPolicyNever:
+		return RestartPolicyNever, nil
+	case RestartPolicyOnFailure, RestartPolicyAlways:
+		return cfg.Restart, nil
+	}
+	return "", fmt.Errorf("unknown restart policy %q (want always, on-failure or never)", cfg.Restart)
+}
+
+// durationOr returns d, or def when d isn't set
+func durationOr(d Duration, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return time.Duration(d)
+}
+
+// backoffDelay is how long to wait before restart attempt n (from 1): the
+// initial backoff doubled per attempt up to the maximum, with the upper half
+// randomized so apps that crashed together don't restart in lockstep
+func backoffDelay(cfg AppConfig, attempt int) time.Duration {
+	initial := durationOr(cfg.RestartBackoff, defaultRestartBackoff)
+	ceiling := durationOr(cfg.RestartBackoffMax, defaultRestartBackoffMax)
+	d := initial
+	for i := 1; i < attempt && d < ceiling; i++ {
+		d *= 2
+	}
+	if d > ceiling {
+		d = ceiling
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// noteStability tracks how long a running app has been healthy, and clears
+// its crash backoff once it is stable. Called after each health check. Must
+// be called with m.mu held.
+func (app *AppState) noteStability(now time.Time) {
+	if !app.Running {
+		return
+	}
+	if !passesHealth(app.HealthStatus) {
+		app.healthySince = time.Time{}
+	} else if app.healthySince.IsZero() {
+		app.healthySince = now
+	}
+	if app.BackoffAttempt > 0 && app.isStable(now) {
+		appLogf(app.Config, "App %s is stable again after %d restart attempts", app.Config.Name, app.BackoffAttempt)
+		app.BackoffAttempt = 0
+	}
+}
+
+// isStable reports whether the current or last run has been up and healthy
+// for StableAfter, so a crash starts the backoff over from the initial
+// delay. Apps without a liveness probe count as healthy while they run. Must be
+// called with m.mu held.
+func (app *AppState) isStable(now time.Time) bool {
+	since := app.healthySince
+	if app.Config.liveURL() == "" {
+		since = app.StartedAt
+	}
+	return !since.IsZero() && now.Sub(since) >= durationOr(app.Config.StableAfter, defaultStableAfter)
+}
+
+// scheduleRestart restarts an app whose process exited on its own (exitErr
+// is nil for a clean exit) if its restart policy says to: after RestartDelay
+// for a clean exit, or after the next backoff delay for a crash. Must be
+// called with m.mu held.
+func (m *Manager) scheduleRestart(app *AppState, exitErr error) {
+	policy, err := app.Config.restartPolicy()
+	if err != nil {
+		appLogf(app.Config, "App %s has %v, not restarting it", app.Config.Name, err)
+		return
+	}
+	if policy == RestartPolicyNever || (policy == RestartPolicyOnFailure && exitErr == nil) {
+		return
+	}
+	if app.Maintenance {
+		appLogf(app.Config, "App %s is in maintenance, not restarting it", app.Config.Name)
+		return
+	}
+
+	reason, delay := RestartExited, durationOr(app.Config.RestartDelay, defaultRestartDelay)
+	if exitErr != nil {
+		if app.crashLooping(time.Now()) {
+			app.quarantine(exitErr, time.Now())
+			return
+		}
+		app.BackoffAttempt++
+		reason, delay = RestartCrashed, backoffDelay(app.Config, app.BackoffAttempt)
+		appLogf(app.Config, "Restarting %s in %s (attempt %d)", app.Config.Name, delay.Round(time.Millisecond), app.BackoffAttempt)
+	} else {
+		appLogf(app.Config, "Restarting %s in %s", app.Config.Name, delay)
+	}
+
+	app.cancelRestart()
+	app.InBackoff = true
+	app.NextRestartAt = time.Now().Add(delay)
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() { m.autoRestart(app, timer, reason) })
+	app.restartTimer = timer
+}
+
+// cancelRestart drops an app's pending automatic restart, if any. Must be
+// called with m.mu held.
+func (app *AppState) cancelRestart() {
+	if app.restartTimer != nil {
+		app.restartTimer.Stop()
+		app.restartTimer = nil
+	}
+	app.InBackoff = false
+	app.NextRestartAt = time.Time{}
+}
+
+// autoRestart runs a restart scheduled by scheduleRestart, unless it was
+// cancelled or superseded since. A failed start counts as another crash.
+func (m *Manager) autoRestart(app *AppState, timer *time.Timer, reason string) {
+	m.mu.Lock()
+	appName := app.Config.Name
+	if app.restartTimer != timer || m.apps[appName] != app {
+		m.mu.Unlock()
+		return
+	}
+	app.cancelRestart()
+	select {
+	case <-m.done:
+		m.mu.Unlock()
+		return // Shutting down
+	default:
+	}
+	if app.Running || app.Starting || app.Stopping {
+		m.mu.Unlock()
+		return
+	}
+	app.Starting = true
+	cfg := app.Config
+	m.mu.Unlock()
+
+	if err := m.launchStarted(app, cfg); err != nil {
+		appLogf(cfg, "Failed to restart %s: %v", appName, err)
+		m.mu.Lock()
+		if !app.Running && !app.Starting && m.apps[appName] == app {
+			m.scheduleRestart(app, err)
+		}
+		m.mu.Unlock()
+		return
+	}
+	m.recordRestart(appName, reason)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// RestartAllReport describes a whole-stack restart. Stopped covers every
+// running app that was restarted; each entry of Tiers is a group of apps
+// started together. Skipped gives the reason each running app was left alone.
+type RestartAllReport struct {
+	OK      bool              `json:"ok"`
+	Error   string            `json:"error,omitempty"`
+	Stopped []BulkResult      `json:"stopped"`
+	Tiers   [][]BulkResult    `json:"tiers"`
+	Skipped map[string]string `json:"skipped,omitempty"`
+}
+
+// restartTiers groups apps into start tiers. Ordered restarts put every
+// app in a later tier than the apps it depends on, the reverse of
+// stopTiers; otherwise all apps share one tier.
+func (m *Manager) restartTiers(names []string, ordered bool) [][]string {
+	if !ordered {
+		return [][]string{names}
+	}
+	tiers := m.stopTiers(names)
+	slices.Reverse(tiers)
+	return tiers
+}
+
+// failed returns the first failed result, if any
+func failed(results []BulkResult) *BulkResult {
+	for i := range results {
+		if !results[i].OK {
+			return &results[i]
+		}
+	}
+	return nil
+}
+
+// RestartAll bounces every running app: it stops them tier by tier in
+// reverse order, dependents first, waits for their processes to exit, then
+// starts them tier by tier, waiting for each tier to become ready before
+// starting the next. Apps in maintenance and apps restarted within their
+// RestartCooldown are left running as they are. It stops at the first
+// failure or when ctx is done.
+func (m *Manager) RestartAll(ctx context.Context, ordered bool) RestartAllReport {
+	report := RestartAllReport{Skipped: map[string]string{}}
+	now := time.Now()
+	names := m.selectApps(func(app *AppState) bool {
+		switch {
+		case !app.Running:
+			return false
+		case app.Maintenance:
+			report.Skipped[app.Config.Name] = "in maintenance"
+			return false
+		case app.Config.RestartCooldown > 0 && now.Sub(app.lastRestart) < time.Duration(app.Config.RestartCooldown):
+			report.Skipped[app.Config.Name] = fmt.Sprintf("restarted %s ago, within its %s cooldown", now.Sub(app.lastRestart).Round(time.Second), time.Duration(app.Config.RestartCooldown))
+			return false
+		}
+		return true
+	})
+	tiers := m.restartTiers(names, ordered)
+
+	stopAndWait := func(ctx context.Context, name string) error {
+		if err := m.StopApp(name); err != nil {
+			return err
+		}
+		return m.waitExited(ctx, name)
+	}
+	for i := len(tiers) - 1; i >= 0; i-- {
+		results := runBulk(ctx, tiers[i], stopAndWait)
+		report.Stopped = append(report.Stopped, results...)
+		if f := failed(results); f != nil {
+			report.Error = "stopping " + f.App + ": " + f.Error
+			return report
+		}
+	}
+
+	startAndWait := func(ctx context.Context, name string) error {
+		if err := m.StartApp(name); err != nil {
+			return err
+		}
+		m.recordRestart(name, RestartManual)
+		if !ordered {
+			return nil
+		}
+		return m.waitReady(ctx, name)
+	}
+	for _, tier := range tiers {
+		results := runBulk(ctx, tier, startAndWait)
+		report.Tiers = append(report.Tiers, results)
+		if f := failed(results); f != nil {
+			report.Error = "starting " + f.App + ": " + f.Error
+			return report
+		}
+	}
+
+	report.OK = true
+	return report
+}
+
+// RollingRestartReport describes a rolling restart. Apps after a failure
+// are left alone and listed in Skipped.
+type RollingRestartReport struct {
+	OK        bool         `json:"ok"`
+	Error     string       `json:"error,omitempty"`
+	Restarted []BulkResult `json:"restarted"`
+	Skipped   []string     `json:"skipped"`
+}
+
+// RollingRestart restarts the running apps matching selector one at a time
+// in StartOrder, waiting up to defaultWaitHealthyTimeout for each to become
+// ready before moving on, so only one of them is down at any moment. It
+// stops at the first app that fails to come back or when ctx is done.
+func (m *Manager) RollingRestart(ctx context.Context, selector map[string]string) RollingRestartReport {
+	report := RollingRestartReport{Restarted: []BulkResult{}, Skipped: []string{}}
+	names := m.selectApps(func(app *AppState) bool { return app.Running && app.Config.matchesLabels(selector) })
+	var order []string
+	for _, tier := range m.startTiers(names) {
+		order = append(order, tier...)
+	}
+
+	for i, name := range order {
+		err := ctx.Err()
+		if err == nil {
+			err = m.RestartApp(name, RestartRolling)
+		}
+		if err == nil {
+			readyCtx, cancel := context.WithTimeout(ctx, defaultWaitHealthyTimeout)
+			err = m.waitReady(readyCtx, name)
+			cancel()
+		}
+		result := BulkResult{App: name, OK: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			report.Restarted = append(report.Restarted, result)
+			report.Skipped = append(report.Skipped, order[i+1:]...)
+			report.Error = fmt.Sprintf("restarting %s: %v", name, err)
+			return report
+		}
+		report.Restarted = append(report.Restarted, result)
+	}
+	report.OK = true
+	return report
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestartTiersFollowDependencies(t *testing.T) {
+	m := newTestManager(t,
+		AppConfig{Name: "db", Priority: 1},
+		AppConfig{Name: "api", Priority: 5, DependsOn: []string{"db"}},
+		AppConfig{Name: "web", Priority: 9, DependsOn: []string{"api"}},
+	)
+	want := [][]string{{"db"}, {"api"}, {"web"}}
+	if got := m.restartTiers([]string{"api", "db", "web"}, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("ordered restartTiers = %v, want %v", got, want)
+	}
+	want = [][]string{{"api", "db", "web"}}
+	if got := m.restartTiers([]string{"api", "db", "web"}, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("unordered restartTiers = %v, want %v", got, want)
+	}
+}
+
+func TestRestartAllSkipsMaintenanceAndCooldown(t *testing.T) {
+	m := newTestManager(t,
+		AppConfig{Name: "web", Path: "sleep", Args: []string{"60"}},
+		AppConfig{Name: "db", Path: "sleep", Args: []string{"60"}},
+		AppConfig{Name: "cache", Path: "sleep", Args: []string{"60"}, RestartCooldown: Duration(time.Hour)},
+	)
+	for _, name := range []string{"web", "db", "cache"} {
+		if err := m.StartApp(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.SetMaintenance("db", true); err != nil {
+		t.Fatal(err)
+	}
+	m.recordRestart("cache", RestartManual) // Just came back
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	report := m.RestartAll(ctx, true)
+	if !report.OK {
+		t.Fatalf("RestartAll failed: %s", report.Error)
+	}
+	if report.Skipped["db"] != "in maintenance" {
+		t.Errorf("db skipped for %q, want maintenance", report.Skipped["db"])
+	}
+	if !strings.Contains(report.Skipped["cache"], "cooldown") {
+		t.Errorf("cache skipped for %q, want its cooldown", report.Skipped["cache"])
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for name, runs := range map[string]int{"web": 2, "db": 1, "cache": 1} {
+		if app := m.apps[name]; app.RunCount != runs || !app.Running {
+			t.Errorf("%s: run count %d (running %v), want %d", name, app.RunCount, app.Running, runs)
+		}
+	}
+}
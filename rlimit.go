@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// fdExhaustionScan is how much trailing output is searched for signs the app
+// ran out of file descriptors
+const fdExhaustionScan = 4096
+
+// limitOpenFiles makes cmd run under prlimit, which caps RLIMIT_NOFILE at max
+// and then execs the app in its place, so the limit holds from the app's
+// first instruction and the pid stays the app's
+func limitOpenFiles(cmd *exec.Cmd, cfg AppConfig, max uint64) error {
+	prlimit, err := exec.LookPath("prlimit")
+	if err != nil {
+		return fmt.Errorf("max_open_files needs prlimit (util-linux): %w", err)
+	}
+	if cfg.Chroot != "" {
+		if _, err := os.Stat(filepath.Join(cfg.Chroot, prlimit)); err != nil {
+			return fmt.Errorf("max_open_files needs %s inside chroot %s: %w", prlimit, cfg.Chroot, err)
+		}
+	}
+	cmd.Args = append([]string{prlimit, fmt.Sprintf("--nofile=%d", max), "--", cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = prlimit
+	return nil
+}
+
+// ranOutOfFiles reports whether an app's final output shows EMFILE errors
+func ranOutOfFiles(output []byte) bool {
+	if len(output) > fdExhaustionScan {
+		output = output[len(output)-fdExhaustionScan:]
+	}
+	return bytes.Contains(bytes.ToLower(output), []byte("too many open files"))
+}
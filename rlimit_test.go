@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxOpenFilesAppliesFromStart(t *testing.T) {
+	// The shell reports its limits as its very first action, then lingers so
+	// the output is read before the run ends
+	m := newTestManager(t, AppConfig{Name: "limited", Path: "ulimit -n; ulimit -Hn; sleep 1", Shell: true, MaxOpenFiles: 64})
+	if err := m.StartApp("limited"); err != nil {
+		t.Fatal(err)
+	}
+	var output string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		m.mu.RLock()
+		output = string(m.apps["limited"].OutputBuffer.Bytes())
+		m.mu.RUnlock()
+		if strings.HasSuffix(output, "\n64\n64\n") {
+			return
+		}
+	}
+	t.Errorf("soft and hard limits reported as %q, want 64 and 64", output)
+}
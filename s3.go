@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// crashUploadTimeout bounds a single crash log upload
+const crashUploadTimeout = 30 * time.Second
+
+// S3Config points at an S3-compatible bucket used to archive crash output
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.us-east-1.amazonaws.com or a MinIO URL
+	Region    string
+	Bucket    string
+	Prefix    string // Prepended to object keys
+	AccessKey string
+	SecretKey string
+}
+
+// s3ConfigFromEnv reads crash archive settings from ALBERT_S3_* environment
+// variables, returning nil when no bucket is configured
+func s3ConfigFromEnv() *S3Config {
+	cfg := &S3Config{
+		Endpoint:  os.Getenv("ALBERT_S3_ENDPOINT"),
+		Region:    os.Getenv("ALBERT_S3_REGION"),
+		Bucket:    os.Getenv("ALBERT_S3_BUCKET"),
+		Prefix:    os.Getenv("ALBERT_S3_PREFIX"),
+		AccessKey: os.Getenv("ALBERT_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("ALBERT_S3_SECRET_KEY"),
+	}
+	if cfg.Bucket == "" || cfg.Endpoint == "" {
+		return nil
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return cfg
+}
+
+// archiveCrash uploads a crashed app's output, logging rather than returning
+// any failure since archiving is best-effort
+func (c *S3Config) archiveCrash(appName string, output []byte, at time.Time) {
+	key := fmt.Sprintf("%s%s/%s.log", c.Prefix, appName, at.UTC().Format("20060102T150405Z"))
+	ctx, cancel := context.WithTimeout(context.Background(), crashUploadTimeout)
+	defer cancel()
+	if err := c.putObject(ctx, key, output); err != nil {
+		log.Printf("Failed to archive crash output for %s to s3://%s/%s: %v", appName, c.Bucket, key, err)
+		return
+	}
+	log.Printf("Archived crash output for %s to s3://%s/%s", appName, c.Bucket, key)
+}
+
+// putObject uploads body to key using a path-style URL and AWS Signature V4.
+// The bucket and key go after any path the endpoint has, e.g. for an S3
+// service behind a reverse proxy at https://example.com/s3.
+func (c *S3Config) putObject(ctx context.Context, key string, body []byte) error {
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	path := strings.TrimSuffix(endpoint.EscapedPath(), "/") + "/" + awsURIEscape(c.Bucket) + "/" + awsURIEscape(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint.Scheme+"://"+endpoint.Host+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	c.sign(req, path, body, time.Now())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// sign adds AWS Signature V4 headers for an S3 request
+func (c *S3Config) sign(req *http.Request, path string, body []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		"", // No query string
+		"content-type:" + req.Header.Get("Content-Type"),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + c.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), day)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKey, scope, signedHeaders, signature))
+}
+
+// sha256Hex returns the hex-encoded SHA-256 of b
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns HMAC-SHA256(key, data)
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsURIEscape percent-encodes everything except unreserved characters and
+// '/', as S3 expects for object key paths
+func awsURIEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPutObjectKeepsEndpointPath(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.EscapedPath()
+	}))
+	defer srv.Close()
+
+	c := &S3Config{Endpoint: srv.URL + "/s3/", Region: "us-east-1", Bucket: "crashes"}
+	if err := c.putObject(context.Background(), "web/crash 1.log", []byte("boom")); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/s3/crashes/web/crash%201.log"; got != want {
+		t.Errorf("uploaded to %s, want %s", got, want)
+	}
+}
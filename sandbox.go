@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Capabilities needed for sandboxing, from linux/capability.h
+const (
+	capSysChroot = 18
+	capSysAdmin  = 21
+)
+
+var namespaceFlags = map[string]uintptr{
+	"mount": syscall.CLONE_NEWNS,
+	"pid":   syscall.CLONE_NEWPID,
+	"net":   syscall.CLONE_NEWNET,
+	"uts":   syscall.CLONE_NEWUTS,
+	"ipc":   syscall.CLONE_NEWIPC,
+}
+
+// hasCapability reports whether albert's effective capability set includes cap
+func hasCapability(cap uint) (bool, error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if hex, ok := strings.CutPrefix(scanner.Text(), "CapEff:"); ok {
+			mask, err := strconv.ParseUint(strings.TrimSpace(hex), 16, 64)
+			if err != nil {
+				return false, err
+			}
+			return mask&(1<<cap) != 0, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, err
+	}
+	return false, fmt.Errorf("no CapEff in /proc/self/status")
+}
+
+// sandboxAttr builds the process attributes for an app's Chroot and
+// Namespaces settings, or returns nil if it has neither. It fails early with
+// a clear error when albert lacks the privilege to apply them.
+func sandboxAttr(cfg AppConfig) (*syscall.SysProcAttr, error) {
+	if cfg.Chroot == "" && len(cfg.Namespaces) == 0 {
+		return nil, nil
+	}
+
+	attr := &syscall.SysProcAttr{Chroot: cfg.Chroot}
+	for _, ns := range cfg.Namespaces {
+		flag, ok := namespaceFlags[ns]
+		if !ok {
+			return nil, fmt.Errorf("app %s has unknown namespace %q", cfg.Name, ns)
+		}
+		attr.Cloneflags |= flag
+	}
+
+	if cfg.Chroot != "" {
+		if ok, err := hasCapability(capSysChroot); err != nil {
+			return nil, fmt.Errorf("failed to check privileges for %s: %w", cfg.Name, err)
+		} else if !ok {
+			return nil, fmt.Errorf("app %s needs CAP_SYS_CHROOT (run albert as root) to chroot into %s", cfg.Name, cfg.Chroot)
+		}
+	}
+	if attr.Cloneflags != 0 {
+		if ok, err := hasCapability(capSysAdmin); err != nil {
+			return nil, fmt.Errorf("failed to check privileges for %s: %w", cfg.Name, err)
+		} else if !ok {
+			return nil, fmt.Errorf("app %s needs CAP_SYS_ADMIN (run albert as root) to create namespaces", cfg.Name)
+		}
+	}
+	return attr, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Scheduled actions
+const (
+	ScheduleStart   = "start"
+	ScheduleStop    = "stop"
+	ScheduleRestart = "restart"
+)
+
+// ScheduleEntry runs an action on an app whenever its cron expression
+// matches: five fields (minute hour day-of-month month day-of-week) or a
+// descriptor like @daily, in albert's local time unless prefixed with
+// CRON_TZ=<zone>
+type ScheduleEntry struct {
+	Cron   string `json:"cron"`
+	Action string `json:"action"` // start, stop or restart
+}
+
+// ScheduledRun is an upcoming scheduled action, as listed by /api/schedule
+type ScheduledRun struct {
+	App    string    `json:"app"`
+	Cron   string    `json:"cron"`
+	Action string    `json:"action"`
+	Next   time.Time `json:"next"`
+}
+
+// parseSchedule checks a schedule entry and parses its cron expression
+func parseSchedule(entry ScheduleEntry) (cron.Schedule, error) {
+	switch entry.Action {
+	case ScheduleStart, ScheduleStop, ScheduleRestart:
+	default:
+		return nil, fmt.Errorf("unknown action %q (want start, stop or restart)", entry.Action)
+	}
+	schedule, err := cron.ParseStandard(entry.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", entry.Cron, err)
+	}
+	return schedule, nil
+}
+
+// syncSchedules replaces the scheduled actions with those of the current
+// apps, starting the scheduler the first time. Called at startup and after
+// every config change.
+func (m *Manager) syncSchedules() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.scheduler == nil {
+		m.scheduler = cron.New()
+		m.scheduler.Start()
+	}
+	for _, entry := range m.scheduler.Entries() {
+		m.scheduler.Remove(entry.ID)
+	}
+	m.scheduled = map[cron.EntryID]ScheduledRun{}
+
+	for _, app := range m.apps {
+		for _, entry := range app.Config.Schedule {
+			schedule, err := parseSchedule(entry)
+			if err != nil {
+				appLogf(app.Config, "Ignoring schedule of %s: %v", app.Config.Name, err)
+				continue
+			}
+			appName, action := app.Config.Name, entry.Action
+			id := m.scheduler.Schedule(schedule, cron.FuncJob(func() { m.runScheduled(appName, action) }))
+			m.scheduled[id] = ScheduledRun{App: appName, Cron: entry.Cron, Action: action}
+		}
+	}
+}
+
+// runScheduled performs a scheduled action. Starting a running app or
+// stopping a stopped one does nothing.
+func (m *Manager) runScheduled(appName, action string) {
+	m.mu.RLock()
+	app, ok := m.apps[appName]
+	running := ok && app.Running
+	m.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	var err error
+	switch action {
+	case ScheduleStart:
+		if running {
+			return
+		}
+		err = m.StartApp(appName)
+	case ScheduleStop:
+		if !running {
+			return
+		}
+		err = m.StopApp(appName)
+	case ScheduleRestart:
+		err = m.RestartApp(appName, RestartScheduled)
+	}
+	if err != nil {
+		appLogf(m.appConfig(appName), "Scheduled %s of %s failed: %v", action, appName, err)
+		return
+	}
+	appLogf(m.appConfig(appName), "Scheduled %s of %s done", action, appName)
+}
+
+// Schedule lists the scheduled actions by when they next run
+func (m *Manager) Schedule() []ScheduledRun {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	runs := []ScheduledRun{}
+	if m.scheduler == nil {
+		return runs
+	}
+	for _, entry := range m.scheduler.Entries() {
+		if run, ok := m.scheduled[entry.ID]; ok {
+			run.Next = entry.Next
+			runs = append(runs, run)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Next.Before(runs[j].Next) })
+	return runs
+}
+
+// scheduleHandler lists the scheduled actions by when they next run
+func scheduleHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(mgr.Schedule()); err != nil {
+		log.Printf("Error encoding schedule: %v", err)
+	}
+}
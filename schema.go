@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// durationPattern matches the Go durations config files use, e.g. "1m30s"
+const durationPattern = `^(0|([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+)$`
+
+// uriFields are settings whose values must be absolute URLs
+var uriFields = map[string]bool{"health_url": true, "ready_url": true, "live_url": true}
+
+var (
+	durationType   = reflect.TypeOf(Duration(0))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil)) // Profile overrides, checked as AppConfig
+	appConfigType  = reflect.TypeOf(AppConfig{})
+	fileConfigType = reflect.TypeOf(FileConfig{})
+)
+
+// configSchema returns a JSON Schema for config files, generated from
+// FileConfig so it always matches what albert accepts. Drop-in files match
+// its $defs/AppConfig.
+func configSchema() map[string]any {
+	schema := schemaFor(fileConfigType, "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "albert config"
+	schema["$defs"] = map[string]any{"AppConfig": schemaFor(appConfigType, "")}
+	schema["properties"].(map[string]any)["apps"] = map[string]any{
+		"type":  "array",
+		"items": map[string]any{"$ref": "#/$defs/AppConfig", "required": []string{"name", "path"}},
+	}
+	return schema
+}
+
+// schemaFor describes a Go type as JSON Schema. field is the JSON name of
+// the setting being described, for format hints.
+func schemaFor(t reflect.Type, field string) map[string]any {
+	switch {
+	case t == durationType:
+		return map[string]any{"type": "string", "pattern": durationPattern}
+	case t == rawMessageType:
+		return map[string]any{"$ref": "#/$defs/AppConfig"} // Profile overrides
+	case t == appConfigType && field != "":
+		return map[string]any{"$ref": "#/$defs/AppConfig"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if uriFields[field] {
+			return map[string]any{"type": "string", "format": "uri"}
+		}
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), "")}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), "")}
+	case reflect.Struct:
+		props := map[string]any{}
+		for _, f := range jsonFields(t) {
+			props[f.name] = schemaFor(f.typ, f.name)
+		}
+		return map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	}
+	return map[string]any{}
+}
+
+// jsonField is an exported struct field as it appears in JSON
+type jsonField struct {
+	name string
+	typ  reflect.Type
+}
+
+// jsonFields lists the fields of a struct type that encoding/json uses
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: f.Type})
+	}
+	return fields
+}
+
+// schemaErrors lists every place a config breaks the schema, one
+// "pointer: problem" entry each
+type schemaErrors []string
+
+func (e schemaErrors) Error() string {
+	return strings.Join(e, "; ")
+}
+
+// checkSchema checks decoded JSON against the schema for t and returns the
+// problems found, or nil. path prefixes every reported location.
+func checkSchema(v any, t reflect.Type, path string) schemaErrors {
+	var errs schemaErrors
+	walkSchema(v, t, path, "", &errs)
+	return errs
+}
+
+// walkSchema checks one value, appending problems to errs
+func walkSchema(v any, t reflect.Type, path, field string, errs *schemaErrors) {
+	report := func(format string, args ...any) {
+		at := path
+		if at == "" {
+			at = "(top level)"
+		}
+		*errs = append(*errs, at+": "+fmt.Sprintf(format, args...))
+	}
+	if v == nil {
+		return // null leaves the setting unset
+	}
+
+	switch {
+	case t == durationType:
+		s, ok := v.(string)
+		if !ok {
+			report("expected a duration like \"30s\", got %s", jsonKind(v))
+		} else if _, err := time.ParseDuration(s); err != nil {
+			report("invalid duration %q", s)
+		}
+		return
+	case t == rawMessageType:
+		t = appConfigType
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			report("expected a string, got %s", jsonKind(v))
+			return
+		}
+		if uriFields[field] && s != "" && !strings.Contains(s, "{{") && !strings.Contains(s, "${") {
+			if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+				report("invalid URL")
+			}
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			report("expected true or false, got %s", jsonKind(v))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := v.(float64)
+		switch {
+		case !ok:
+			report("expected a whole number, got %s", jsonKind(v))
+		case n != float64(int64(n)):
+			report("expected a whole number, got %v", n)
+		case n < 0 && t.Kind() >= reflect.Uint:
+			report("must not be negative")
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(float64); !ok {
+			report("expected a number, got %s", jsonKind(v))
+		}
+	case reflect.Slice:
+		items, ok := v.([]any)
+		if !ok {
+			report("expected a list, got %s", jsonKind(v))
+			return
+		}
+		for i, item := range items {
+			walkSchema(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), "", errs)
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			report("expected an object, got %s", jsonKind(v))
+			return
+		}
+		for _, k := range sortedKeys(obj) {
+			walkSchema(obj[k], t.Elem(), joinPointer(path, k), "", errs)
+		}
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			report("expected an object, got %s", jsonKind(v))
+			return
+		}
+		fields := map[string]reflect.Type{}
+		var names []string
+		for _, f := range jsonFields(t) {
+			fields[f.name] = f.typ
+			names = append(names, f.name)
+		}
+		for _, k := range sortedKeys(obj) {
+			ft, ok := fields[k]
+			if !ok {
+				msg := "unknown setting"
+				if guess := closestName(k, names); guess != "" {
+					msg += fmt.Sprintf(" (did you mean %s?)", guess)
+				}
+				*errs = append(*errs, joinPointer(path, k)+": "+msg)
+				continue
+			}
+			walkSchema(obj[k], ft, joinPointer(path, k), k, errs)
+		}
+	}
+}
+
+// joinPointer appends an object key to a location like apps[2].env
+func joinPointer(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonKind names the JSON type of a decoded value
+func jsonKind(v any) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "true/false"
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	}
+	return "null"
+}
+
+// sortedKeys returns an object's keys in order, for stable error output
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// closestName returns the name within edit distance 2 of s, if any, to
+// suggest for a misspelled setting
+func closestName(s string, names []string) string {
+	best, bestDist := "", 3
+	for _, name := range names {
+		if d := editDistance(s, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// writeConfigSchema writes the config file JSON Schema, indented
+func writeConfigSchema(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(configSchema())
+}
+
+// configSchemaHandler serves the config file JSON Schema
+func configSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	if err := writeConfigSchema(w); err != nil {
+		log.Printf("Error encoding config schema: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// secretScheme prefixes config values that name a secret instead of holding
+// the value itself
+const secretScheme = "secret://"
+
+// redactedSecret replaces resolved secret values in captured output
+const redactedSecret = "[REDACTED]"
+
+// SecretProvider looks up secret values by name
+type SecretProvider interface {
+	Secret(name string) (string, error)
+}
+
+// secretProvider resolves secret:// references; nil when none is configured
+var secretProvider SecretProvider
+
+// fileSecrets reads each secret from a file of the same name in Dir, as with
+// Docker or Kubernetes mounted secrets. A trailing newline is dropped.
+type fileSecrets struct {
+	Dir string
+}
+
+func (s fileSecrets) Secret(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(s.Dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// vaultSecrets reads secrets from a Vault KV v2 engine. Names have the form
+// path/to/secret#field, with the field defaulting to "value".
+type vaultSecrets struct {
+	Addr  string
+	Token string
+	Mount string
+}
+
+// vaultTimeout bounds a single Vault request
+const vaultTimeout = 10 * time.Second
+
+func (s vaultSecrets) Secret(name string) (string, error) {
+	path, field, ok := strings.Cut(name, "#")
+	if !ok {
+		field = "value"
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(s.Addr, "/")+"/v1/"+s.Mount+"/data/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", s.Token)
+	client := http.Client{Timeout: vaultTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return "", fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response for %s: %w", path, err)
+	}
+	v, ok := body.Data.Data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no string field %q", path, field)
+	}
+	return v, nil
+}
+
+// secretProviderFromEnv picks the secrets backend: Vault when VAULT_ADDR is
+// set (with VAULT_TOKEN, and ALBERT_VAULT_MOUNT defaulting to "secret"),
+// otherwise files in ALBERT_SECRETS_DIR. Returns nil if neither is set.
+func secretProviderFromEnv() SecretProvider {
+	if addr := os.Getenv("VAULT_ADDR"); addr != "" {
+		mount := os.Getenv("ALBERT_VAULT_MOUNT")
+		if mount == "" {
+			mount = "secret"
+		}
+		return vaultSecrets{Addr: addr, Token: os.Getenv("VAULT_TOKEN"), Mount: mount}
+	}
+	if dir := os.Getenv("ALBERT_SECRETS_DIR"); dir != "" {
+		return fileSecrets{Dir: dir}
+	}
+	return nil
+}
+
+// resolveSecrets returns a copy of cfg with secret:// references in Args and
+// Env values replaced by their values, plus a replacer that redacts those
+// values (nil if there were none). The stored config keeps the references,
+// so resolved values never show up in the API.
+func resolveSecrets(cfg AppConfig) (AppConfig, *strings.Replacer, error) {
+	var values []string
+	resolve := func(v string) (string, error) {
+		name, ok := strings.CutPrefix(v, secretScheme)
+		if !ok {
+			return v, nil
+		}
+		if secretProvider == nil {
+			return "", fmt.Errorf("app %s references secret %s but no secrets backend is configured", cfg.Name, name)
+		}
+		secret, err := secretProvider.Secret(name)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve secret %s for %s: %w", name, cfg.Name, err)
+		}
+		if secret != "" {
+			values = append(values, secret, redactedSecret)
+		}
+		return secret, nil
+	}
+
+	args := make([]string, len(cfg.Args))
+	for i, arg := range cfg.Args {
+		var err error
+		if args[i], err = resolve(arg); err != nil {
+			return AppConfig{}, nil, err
+		}
+	}
+	cfg.Args = args
+	env := make(map[string]string, len(cfg.Env))
+	for k, v := range cfg.Env {
+		var err error
+		if env[k], err = resolve(v); err != nil {
+			return AppConfig{}, nil, err
+		}
+	}
+	cfg.Env = env
+
+	if len(values) == 0 {
+		return cfg, nil, nil
+	}
+	return cfg, strings.NewReplacer(values...), nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// appCommand builds the command that runs an app. Shell apps run Path as an
+// sh -c script with Args as its positional parameters ($1, $2, ...).
+func appCommand(cfg AppConfig) *exec.Cmd {
+	if !cfg.Shell {
+		return exec.Command(cfg.Path, cfg.Args...)
+	}
+	return exec.Command("/bin/sh", append([]string{"-c", cfg.Path, cfg.Name}, cfg.Args...)...)
+}
+
+// signalApp sends sig to an app's process group, so children it spawned get
+// it too. Returns os.ErrProcessDone if nothing was left to signal.
+func signalApp(proc *os.Process, sig syscall.Signal) error {
+	err := syscall.Kill(-proc.Pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// killApp kills an app's process. Apps are started in their own process
+// group, which is killed as a whole so no child (an ffmpeg encoder, a
+// pipeline stage) is left behind holding ports or devices. Returns
+// os.ErrProcessDone if nothing was left to kill.
+func killApp(proc *os.Process) error {
+	return signalApp(proc, syscall.SIGKILL)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// What happens to a running app when albert itself receives SIGTERM or
+// SIGINT
+const (
+	ManagerExitStop   = "stop"   // Default: stopped as by StopApp
+	ManagerExitDetach = "detach" // Left running on its own
+)
+
+// managerExitPolicy returns the app's on_manager_exit policy, or an error
+// for an unknown one
+func (cfg AppConfig) managerExitPolicy() (string, error) {
+	switch cfg.OnManagerExit {
+	case "", ManagerExitStop:
+		return ManagerExitStop, nil
+	case ManagerExitDetach:
+		return ManagerExitDetach, nil
+	}
+	return "", fmt.Errorf("unknown policy %q (want stop or detach)", cfg.OnManagerExit)
+}
+
+// exitOnSignal handles SIGTERM and SIGINT by applying the apps'
+// on_manager_exit policies and exiting. A second signal exits right away.
+func (m *Manager) exitOnSignal() {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-sigs
+	log.Printf("Received %v, shutting down", sig)
+	go func() {
+		sig := <-sigs
+		log.Printf("Received %v again, exiting without waiting for apps", sig)
+		os.Exit(1)
+	}()
+	m.ExitApps()
+	log.Printf("Shut down")
+	os.Exit(0)
+}
+
+// ExitApps gets the apps ready for albert to exit: background work stops so
+// nothing is restarted, running jobs are cancelled, apps with the stop policy
+// are stopped, dependents first, and detached apps are left running, writing
+// their output to their OutputFile.
+func (m *Manager) ExitApps() {
+	m.Shutdown()
+
+	var stop []string
+	m.mu.Lock()
+	for _, name := range sortedAppNames(m.apps) {
+		app := m.apps[name]
+		app.cancelRestart()
+		if !app.Running || app.Cmd == nil || app.Cmd.Process == nil {
+			continue
+		}
+		policy, err := app.Config.managerExitPolicy()
+		if err != nil {
+			appLogf(app.Config, "App %s has on_manager_exit: %v, stopping it", name, err)
+			policy = ManagerExitStop
+		}
+		if policy == ManagerExitDetach {
+			appLogf(app.Config, "Leaving %s running as pid %d", name, app.Cmd.Process.Pid)
+			continue
+		}
+		stop = append(stop, name)
+	}
+	m.mu.Unlock()
+
+	m.jobsMu.Lock()
+	for _, job := range m.jobs {
+		if job.Status == JobRunning {
+			job.cancelled = true
+			job.cancel()
+		}
+	}
+	m.jobsMu.Unlock()
+
+	for _, result := range m.StopOrdered(context.Background(), stop) {
+		if !result.OK {
+			log.Printf("Failed to stop %s on exit: %s", result.App, result.Error)
+		}
+	}
+	m.saveRuns() // Leaves the detached apps for the next albert to adopt
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExitAppsWhileOutputFlows(t *testing.T) {
+	// On SIGTERM the app writes far more than a pipe holds before exiting
+	// cleanly, which only works while albert keeps reading its output
+	script := `trap 'head -c 1000000 /dev/zero | tr "\0" x; exit 0' TERM
+while :; do echo working; sleep 0.01; done`
+	m := newTestManager(t, AppConfig{Name: "chatty", Path: script, Shell: true, StopTimeout: Duration(5 * time.Second)})
+	if err := m.StartApp("chatty"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		m.ExitApps()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("ExitApps did not return")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	app := m.apps["chatty"]
+	if app.Running {
+		t.Fatal("app still running after ExitApps")
+	}
+	if app.LastExitCode == nil {
+		t.Fatal("no exit code recorded")
+	}
+	if *app.LastExitCode != 0 {
+		t.Errorf("exit code = %d, want a clean exit rather than a kill after blocking on output", *app.LastExitCode)
+	}
+}
+
+func TestDetachedAppOutputOutlivesAlbert(t *testing.T) {
+	dir := t.TempDir()
+	cfg := AppConfig{
+		Name:          "detached",
+		Path:          "i=0; while :; do echo line $i; i=$((i+1)); sleep 0.05; done",
+		Shell:         true,
+		OnManagerExit: ManagerExitDetach,
+	}
+	lines := func(m *Manager) int {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return strings.Count(string(m.apps["detached"].OutputBuffer.Bytes()), "line ")
+	}
+	waitLines := func(m *Manager, want int) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); lines(m) < want; time.Sleep(20 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("only %d output lines followed, want %d", lines(m), want)
+			}
+		}
+	}
+
+	first := newTestManager(t, cfg)
+	first.stateFile = filepath.Join(dir, "state.json")
+	if err := first.StartApp("detached"); err != nil {
+		t.Fatal(err)
+	}
+	waitLines(first, 2)
+	first.mu.RLock()
+	pid := first.apps["detached"].Cmd.Process.Pid
+	first.mu.RUnlock()
+	first.ExitApps()
+	written := func() int {
+		data, err := os.ReadFile(filepath.Join(dir, "albert-detached.out"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(string(data), "line ")
+	}
+	atExit := written()
+
+	// With pipes it would die of SIGPIPE at its next line
+	time.Sleep(300 * time.Millisecond)
+	if _, err := procStartTime(pid); err != nil {
+		t.Fatalf("detached app exited once albert was gone: %v", err)
+	}
+	if n := written(); n <= atExit {
+		t.Errorf("output file stuck at %d lines after albert exited", n)
+	}
+
+	// A new albert adopts it and follows its output from there on
+	second := newTestManager(t, cfg)
+	second.stateFile = first.stateFile
+	second.adoptRuns()
+	second.mu.RLock()
+	adopted, output := second.apps["detached"].Running, second.apps["detached"].outputFile
+	second.mu.RUnlock()
+	if !adopted {
+		t.Fatal("detached app not adopted")
+	}
+	if want := filepath.Join(dir, "albert-detached.out"); output != want {
+		t.Errorf("output file = %q, want %q", output, want)
+	}
+	waitLines(second, 2)
+}
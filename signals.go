@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// signalNames maps the signals albert can send to apps by name
+var signalNames = map[string]syscall.Signal{
+	"SIGHUP":   syscall.SIGHUP,
+	"SIGINT":   syscall.SIGINT,
+	"SIGQUIT":  syscall.SIGQUIT,
+	"SIGKILL":  syscall.SIGKILL,
+	"SIGUSR1":  syscall.SIGUSR1,
+	"SIGUSR2":  syscall.SIGUSR2,
+	"SIGTERM":  syscall.SIGTERM,
+	"SIGCONT":  syscall.SIGCONT,
+	"SIGSTOP":  syscall.SIGSTOP,
+	"SIGTSTP":  syscall.SIGTSTP,
+	"SIGWINCH": syscall.SIGWINCH,
+}
+
+// parseSignal reads a signal by name, with or without the SIG prefix and in
+// any case ("SIGINT", "int"), or by number
+func parseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 && n < 65 {
+		return syscall.Signal(n), nil
+	}
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig, ok := signalNames[name]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal %q", s)
+}
+
+// signalName returns a signal's name, e.g. "SIGTERM"
+func signalName(sig syscall.Signal) string {
+	for name, s := range signalNames {
+		if s == sig {
+			return name
+		}
+	}
+	return fmt.Sprintf("signal %d", int(sig))
+}
+
+// stopSignal returns the signal that asks an app to exit: its StopSignal,
+// or SIGTERM
+func (cfg AppConfig) stopSignal() (syscall.Signal, error) {
+	if cfg.StopSignal == "" {
+		return syscall.SIGTERM, nil
+	}
+	return parseSignal(cfg.StopSignal)
+}
+
+// reloadSignal returns the signal that asks an app to reload its config:
+// its ReloadSignal, or SIGHUP
+func (cfg AppConfig) reloadSignal() (syscall.Signal, error) {
+	if cfg.ReloadSignal == "" {
+		return syscall.SIGHUP, nil
+	}
+	return parseSignal(cfg.ReloadSignal)
+}
+
+// ReloadApp sends a running app its reload signal, so it picks up changes
+// to its own config without a restart
+func (m *Manager) ReloadApp(appName string) error {
+	sig, err := m.appConfig(appName).reloadSignal()
+	if err != nil {
+		return fmt.Errorf("app %s has an invalid reload_signal: %w", appName, err)
+	}
+	return m.SignalApp(appName, sig)
+}
+
+// SignalApp sends sig to a running app's process group, e.g. SIGHUP to
+// reload its config. SIGSTOP and SIGCONT are refused; pause and resume the
+// app instead so albert knows it is suspended.
+func (m *Manager) SignalApp(appName string, sig syscall.Signal) error {
+	if sig == syscall.SIGSTOP || sig == syscall.SIGCONT {
+		return fmt.Errorf("use pause and resume instead of sending %s", signalName(sig))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	app, err := m.signalableApp(appName)
+	if err != nil {
+		return err
+	}
+	if err := signalApp(app.Cmd.Process, sig); err != nil {
+		return fmt.Errorf("failed to signal app %s: %w", appName, err)
+	}
+	appLogf(app.Config, "Sent %s to app: %s", signalName(sig), appName)
+	return nil
+}
+
+// signalAppHandler sends the signal named in the request body to an app,
+// either as plain text ("SIGHUP", "hup" or a number) or as
+// {"signal": "SIGHUP"}
+func signalAppHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	appName := r.PathValue("name")
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(string(body))
+	if strings.HasPrefix(name, "{") {
+		var req struct {
+			Signal string `json:"signal"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		name = req.Signal
+	}
+	sig, err := parseSignal(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if sig == syscall.SIGSTOP || sig == syscall.SIGCONT {
+		http.Error(w, "Use action=pause or action=resume to suspend or continue an app", http.StatusBadRequest)
+		return
+	}
+
+	mgr.mu.RLock()
+	_, ok := mgr.apps[appName]
+	mgr.mu.RUnlock()
+	if !ok {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+	if err := mgr.SignalApp(appName, sig); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "{\"status\": \"success\", \"message\": \"sent %s to app %s\"}", signalName(sig), appName)
+}
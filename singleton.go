@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// singletonLockPath returns the lockfile guarding a singleton app
+func singletonLockPath(cfg AppConfig) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, cfg.Name)
+	return filepath.Join(os.TempDir(), "albert-"+name+".lock")
+}
+
+// acquireSingletonLock takes an exclusive flock on the app's lockfile. The
+// returned file is handed to the child process so the lock is held for as
+// long as the app runs, even if albert itself restarts.
+func acquireSingletonLock(cfg AppConfig) (*os.File, error) {
+	path := singletonLockPath(cfg)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lockfile for %s: %w", cfg.Name, err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("app %s is already running elsewhere (lock held on %s)", cfg.Name, path)
+		}
+		return nil, fmt.Errorf("failed to lock %s for %s: %w", path, cfg.Name, err)
+	}
+	return f, nil
+}
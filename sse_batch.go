@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/RoughCookiexx/gg_sse"
+)
+
+// maxEventBatch caps how many events are held for one window. Past it the
+// oldest pending events are dropped.
+const maxEventBatch = 256
+
+// eventBatcher coalesces SSE messages sent within a window into a single
+// message holding a JSON array of them. With no window every message is sent
+// as soon as it arrives.
+type eventBatcher struct {
+	send func([]byte)
+
+	mu      sync.Mutex
+	window  time.Duration
+	pending [][]byte
+	timer   *time.Timer
+}
+
+// sseEvents is the batcher all non-critical SSE messages go through
+var sseEvents = &eventBatcher{send: sse.SendBytes}
+
+// SetWindow sets how long messages are collected before being sent together
+func (b *eventBatcher) SetWindow(window time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.window = window
+}
+
+// Send queues a JSON message for the current window, opening one if needed
+func (b *eventBatcher) Send(msg []byte) {
+	b.mu.Lock()
+	if b.window <= 0 {
+		b.mu.Unlock()
+		b.send(msg)
+		return
+	}
+	defer b.mu.Unlock()
+	if len(b.pending) == maxEventBatch {
+		b.pending = b.pending[1:]
+	}
+	b.pending = append(b.pending, msg)
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+}
+
+// flush sends the messages collected during the window that just ended
+func (b *eventBatcher) flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.timer = nil
+	b.mu.Unlock()
+
+	switch len(pending) {
+	case 0:
+	case 1:
+		b.send(pending[0])
+	default:
+		batch := append([]byte{'['}, bytes.Join(pending, []byte{','})...)
+		b.send(append(batch, ']'))
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+// startTiers groups apps by StartOrder, lowest first. Apps in a tier are
+// started together; names are sorted within each tier.
+func (m *Manager) startTiers(names []string) [][]string {
+	m.mu.RLock()
+	byOrder := map[int][]string{}
+	for _, name := range names {
+		if app, ok := m.apps[name]; ok {
+			byOrder[app.Config.StartOrder] = append(byOrder[app.Config.StartOrder], name)
+		}
+	}
+	m.mu.RUnlock()
+
+	orders := make([]int, 0, len(byOrder))
+	for order := range byOrder {
+		orders = append(orders, order)
+	}
+	sort.Ints(orders)
+
+	tiers := make([][]string, 0, len(orders))
+	for _, order := range orders {
+		tier := byOrder[order]
+		sort.Strings(tier)
+		tiers = append(tiers, tier)
+	}
+	return tiers
+}
+
+// StartOrdered starts apps tier by tier in StartOrder. With waitReady each
+// app has up to defaultWaitHealthyTimeout to become ready (see waitReady)
+// before the next tier starts; otherwise tiers only start in sequence. A
+// failed app doesn't hold up later tiers. Returns a result per app; apps
+// not reached before ctx is done are reported as timed out.
+func (m *Manager) StartOrdered(ctx context.Context, names []string, waitReady bool) []BulkResult {
+	start := func(ctx context.Context, name string) error {
+		m.mu.RLock()
+		app, ok := m.apps[name]
+		busy := ok && (app.Running || app.Starting) // Started meanwhile, e.g. as a dependency
+		m.mu.RUnlock()
+		if !busy {
+			if err := m.StartApp(name); err != nil {
+				return err
+			}
+		}
+		if !waitReady {
+			return nil
+		}
+		readyCtx, cancel := context.WithTimeout(ctx, defaultWaitHealthyTimeout)
+		defer cancel()
+		return m.waitReady(readyCtx, name)
+	}
+
+	var results []BulkResult
+	for _, tier := range m.startTiers(names) {
+		if ctx.Err() != nil {
+			for _, name := range tier {
+				results = append(results, BulkResult{App: name, TimedOut: true, Error: "timed out"})
+			}
+			continue
+		}
+		results = append(results, runBulk(ctx, tier, start)...)
+	}
+	return results
+}
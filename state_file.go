@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// adoptedPollInterval is how often an adopted process is checked for having
+// exited, since albert can't wait on a process it didn't start
+const adoptedPollInterval = time.Second
+
+// errAdoptedExit is the exit error of an adopted process, whose real exit
+// status went to its new parent
+var errAdoptedExit = errors.New("exited after being adopted; exit status unknown")
+
+// savedRun is a running app's process as remembered in the state file
+type savedRun struct {
+	PID       int       `json:"pid"`
+	StartTime uint64    `json:"start_time"` // In clock ticks since boot, to tell a reused pid apart
+	StartedAt time.Time `json:"started_at"`
+	Output    string    `json:"output,omitempty"` // File a detached app writes its output to
+}
+
+// savedState is the content of the state file
+type savedState struct {
+	Apps map[string]savedRun `json:"apps"`
+}
+
+// procStartTime returns when a live process started, in clock ticks since
+// boot, from field 22 of /proc/<pid>/stat. Zombies count as gone.
+func procStartTime(pid int) (uint64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// The command name in field 2 may contain spaces; the fields after it don't
+	end := strings.LastIndexByte(string(data), ')')
+	if end < 0 {
+		return 0, fmt.Errorf("unexpected stat format for pid %d", pid)
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("unexpected stat format for pid %d", pid)
+	}
+	if fields[0] == "Z" {
+		return 0, fmt.Errorf("pid %d has exited", pid)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// runsChanged asks for the state file to be rewritten. Safe to call with
+// m.mu held.
+func (m *Manager) runsChanged() {
+	if m.stateDirty == nil {
+		return
+	}
+	select {
+	case m.stateDirty <- struct{}{}:
+	default: // A save is already pending
+	}
+}
+
+// persistRuns rewrites the state file whenever apps start or stop
+func (m *Manager) persistRuns() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-m.stateDirty:
+			m.saveRuns()
+		}
+	}
+}
+
+// saveRuns writes the running apps' processes to the state file
+func (m *Manager) saveRuns() {
+	if m.stateFile == "" {
+		return
+	}
+	state := savedState{Apps: map[string]savedRun{}}
+	m.mu.RLock()
+	for name, app := range m.apps {
+		if app.Running && app.Cmd != nil && app.Cmd.Process != nil {
+			state.Apps[name] = savedRun{PID: app.Cmd.Process.Pid, StartedAt: app.StartedAt, Output: app.outputFile}
+		}
+	}
+	m.mu.RUnlock()
+	for name, run := range state.Apps {
+		start, err := procStartTime(run.PID)
+		if err != nil {
+			delete(state.Apps, name) // Already gone
+			continue
+		}
+		run.StartTime = start
+		state.Apps[name] = run
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = writeConfigData(m.stateFile, data)
+	}
+	if err != nil {
+		log.Printf("Failed to save app state to %s: %v", m.stateFile, err)
+	}
+}
+
+// adoptRuns re-adopts the apps that the state file says were running when
+// albert last exited and that still are: same pid and same process start
+// time. They show as running again and can be stopped, signalled and health
+// checked. Detached apps write their output to a file (see OutputFile), which
+// is followed again from its current end. Any other app's output went to
+// the previous albert's pipes and is lost, and it gets SIGPIPE once it
+// writes more unless it ignores it.
+func (m *Manager) adoptRuns() {
+	data, err := os.ReadFile(m.stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var state savedState
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil {
+		log.Printf("Not adopting apps from %s: %v", m.stateFile, err)
+		return
+	}
+
+	names := make([]string, 0, len(state.Apps))
+	for name := range state.Apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		run := state.Apps[name]
+		if start, err := procStartTime(run.PID); err != nil || start != run.StartTime {
+			continue // Exited, and the pid may have been reused since
+		}
+		m.mu.Lock()
+		app, ok := m.apps[name]
+		if !ok || app.Running || app.Starting {
+			m.mu.Unlock()
+			log.Printf("Leaving pid %d alone: app %s is no longer configured or already running", run.PID, name)
+			continue
+		}
+		proc, err := os.FindProcess(run.PID)
+		if err != nil {
+			m.mu.Unlock()
+			continue
+		}
+		var follower *outputFollower
+		if run.Output != "" && run.Output != os.DevNull {
+			if follower, err = followOutput(run.Output); err != nil {
+				appLogf(app.Config, "Not following the output of adopted %s: %v", name, err)
+			}
+		}
+		cmd := &exec.Cmd{Process: proc}
+		cfg := app.Config
+		app.Cmd = cmd
+		app.Running = true
+		app.StartedAt = run.StartedAt
+		app.LastRequest = time.Now()
+		app.RunCount++
+		app.Ready = cfg.readyURL() == ""
+		if !app.Ready {
+			app.HealthStatus = healthStarting
+			go m.gateReady(app, cfg, cmd)
+		}
+		if path := watchedBinary(cfg); path != "" {
+			app.binaryID, _ = statFileID(path) // Assume it hasn't changed since
+		}
+		app.outputFile = run.Output
+		if follower != nil {
+			follower.watch(run.PID)
+			fmt.Fprintf(app.OutputBuffer, "--- %s adopted at %s (pid %d); its earlier output is in %s ---\n", name, time.Now().Format(time.RFC3339), run.PID, run.Output)
+		} else {
+			fmt.Fprintf(app.OutputBuffer, "--- %s adopted at %s (pid %d); its earlier output was lost ---\n", name, time.Now().Format(time.RFC3339), run.PID)
+		}
+		app.exited = m.watchAdopted(app, cmd, run.StartTime)
+		hub.Publish(EventAppState, app.stateEvent())
+		m.mu.Unlock()
+		appLogf(cfg, "Adopted %s, still running as pid %d since %s", name, run.PID, run.StartedAt.Format(time.RFC3339))
+		if follower != nil {
+			_, redact, _ := resolveSecrets(cfg) // Only for redaction; the app already has its secrets
+			go m.readOutput(app, cfg, follower, redact)
+		}
+	}
+	m.runsChanged()
+}
+
+// watchAdopted polls an adopted process until it is gone and then reports
+// the exit like watchExit
+func (m *Manager) watchAdopted(app *AppState, cmd *exec.Cmd, startTime uint64) chan struct{} {
+	exited := make(chan struct{})
+	started := app.StartedAt
+	go func() {
+		ticker := time.NewTicker(adoptedPollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if start, err := procStartTime(cmd.Process.Pid); err != nil || start != startTime {
+				break
+			}
+		}
+		close(exited)
+		m.reportExit(app, cmd, started, errAdoptedExit)
+	}()
+	return exited
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// statusLogLines is how many output lines each app shows on the status page
+const statusLogLines = 5
+
+var statusTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>albert status</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.4em 0.8em; border-bottom: 1px solid #ddd; vertical-align: top; }
+pre { margin: 0; font-size: 0.85em; white-space: pre-wrap; }
+.up { color: #1a7f37; } .down { color: #999; } .bad { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>albert</h1>
+<table>
+<tr><th>App</th><th>Running</th><th>Health</th><th>Uptime</th><th>Recent output</th></tr>
+{{range .}}<tr>
+<td>{{.Name}}</td>
+<td class="{{if .Running}}up{{else}}down{{end}}">{{if .Running}}yes{{else}}no{{end}}</td>
+<td class="{{if .Healthy}}up{{else if .Running}}bad{{else}}down{{end}}">{{.Health}}</td>
+<td>{{.Uptime}}</td>
+<td><pre>{{range .Lines}}{{.}}
+{{end}}</pre></td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// statusRow is one app's line on the status page
+type statusRow struct {
+	Name    string
+	Running bool
+	Healthy bool
+	Health  string
+	Uptime  string
+	Lines   []string
+}
+
+// lastLines returns up to n trailing non-empty lines of output
+func lastLines(output []byte, n int) []string {
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines
+}
+
+// statusPageHandler renders an auto-refreshing HTML overview of all apps
+func statusPageHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	mgr.mu.RLock()
+	rows := make([]statusRow, 0, len(mgr.apps))
+	for _, app := range mgr.apps {
+		row := statusRow{
+			Name:    app.Config.Name,
+			Running: app.Running,
+			Healthy: passesHealth(app.HealthStatus),
+			Health:  app.HealthStatus,
+			Lines:   lastLines(app.outputSnapshot(), statusLogLines),
+		}
+		if app.Running {
+			row.Uptime = time.Since(app.StartedAt).Round(time.Second).String()
+		}
+		rows = append(rows, row)
+	}
+	mgr.mu.RUnlock()
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusTemplate.Execute(w, rows); err != nil {
+		log.Printf("Error rendering status page: %v", err)
+	}
+}
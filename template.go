@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// templateData is what {{...}} templates in an app's config can refer to
+type templateData struct {
+	Name string
+	Port int
+	Vars map[string]string
+}
+
+// renderTemplate fills in {{.Name}}, {{.Port}} and {{.Vars.key}} references
+// in s from cfg. Strings without "{{" are returned as is.
+func renderTemplate(s string, cfg AppConfig) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	tmpl, err := template.New(cfg.Name).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", s, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, templateData{Name: cfg.Name, Port: cfg.Port, Vars: cfg.Vars}); err != nil {
+		return "", fmt.Errorf("failed to fill in template %q: %w", s, err)
+	}
+	return out.String(), nil
+}
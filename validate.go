@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationReport lists the problems found in a config
+type ValidationReport struct {
+	Valid  bool                `json:"valid"`
+	Errors []string            `json:"errors,omitempty"` // Problems with the config as a whole
+	Apps   map[string][]string `json:"apps,omitempty"`   // Problems by app name
+}
+
+// appIssue records a problem with one app
+func (r *ValidationReport) appIssue(appName, format string, args ...any) {
+	if r.Apps == nil {
+		r.Apps = make(map[string][]string)
+	}
+	r.Apps[appName] = append(r.Apps[appName], fmt.Sprintf(format, args...))
+}
+
+// validateConfigFile checks a config file with a profile applied and
+// overlaid with overlay, as albert would load it
+func validateConfigFile(path, profile string, overlay []AppConfig) ValidationReport {
+	fc, err := parseConfigFile(path)
+	var errs schemaErrors
+	if errors.As(err, &errs) {
+		return ValidationReport{Errors: errs} // One entry per problem
+	} else if err != nil {
+		return ValidationReport{Errors: []string{err.Error()}}
+	}
+	apps, err := fc.applyProfile(profile)
+	if err != nil {
+		return ValidationReport{Errors: []string{err.Error()}}
+	}
+	return checkConfigs(mergeConfigs(apps, overlay))
+}
+
+// checkConfigs checks that app names are unique, binaries exist and are
+// executable, health URLs parse, dependencies exist without cycles and no
+// two apps use the same port
+func checkConfigs(configs []AppConfig) ValidationReport {
+	var report ValidationReport
+	seen := map[string]bool{}
+	ports := map[int][]string{}
+	for i, raw := range configs {
+		if raw.Name == "" {
+			report.Errors = append(report.Errors, fmt.Sprintf("app #%d has no name", i+1))
+			continue
+		}
+		if seen[raw.Name] {
+			report.appIssue(raw.Name, "defined more than once")
+			continue
+		}
+		seen[raw.Name] = true
+
+		cfg, err := expandConfig(raw)
+		if err != nil {
+			report.appIssue(raw.Name, "%v", err)
+			continue
+		}
+		if err := checkBinary(cfg); err != nil {
+			report.appIssue(cfg.Name, "%v", err)
+		}
+		if _, err := cfg.stopSignal(); err != nil {
+			report.appIssue(cfg.Name, "stop_signal: %v", err)
+		}
+		if _, err := cfg.reloadSignal(); err != nil {
+			report.appIssue(cfg.Name, "reload_signal: %v", err)
+		}
+		if _, err := cfg.managerExitPolicy(); err != nil {
+			report.appIssue(cfg.Name, "on_manager_exit: %v", err)
+		}
+		if _, err := cfg.restartPolicy(); err != nil {
+			report.appIssue(cfg.Name, "restart: %v", err)
+		}
+		for _, entry := range cfg.Schedule {
+			if _, err := parseSchedule(entry); err != nil {
+				report.appIssue(cfg.Name, "schedule: %v", err)
+			}
+		}
+		if err := checkWorkDir(cfg); err != nil {
+			report.appIssue(cfg.Name, "%v", err)
+		}
+		if cfg.User != "" {
+			if _, err := lookupUser(cfg.User); err != nil {
+				report.appIssue(cfg.Name, "user: %v", err)
+			}
+		}
+		if cfg.Group != "" {
+			if _, err := lookupGroup(cfg.Group); err != nil {
+				report.appIssue(cfg.Name, "group: %v", err)
+			}
+		}
+		if err := checkHealthURL(cfg.HealthURL); err != nil {
+			report.appIssue(cfg.Name, "health_url: %v", err)
+		}
+		if err := checkHealthURL(cfg.ReadyURL); err != nil {
+			report.appIssue(cfg.Name, "ready_url: %v", err)
+		}
+		if err := checkHealthURL(cfg.LiveURL); err != nil {
+			report.appIssue(cfg.Name, "live_url: %v", err)
+		}
+		if cfg.StartTimeout > 0 && cfg.readyURL() == "" {
+			report.appIssue(cfg.Name, "start_timeout needs a ready_url or health_url to tell when the app is ready")
+		}
+		if _, err := newOutputBuffer(cfg); err != nil {
+			report.appIssue(cfg.Name, "%v", err)
+		}
+		if port := listenPort(cfg); port > 0 {
+			ports[port] = append(ports[port], cfg.Name)
+		}
+		if cfg.DeployPort > 0 {
+			if err := checkDeployPort(cfg); err != nil {
+				report.appIssue(cfg.Name, "deploy_port: %v", err)
+			} else {
+				ports[cfg.DeployPort] = append(ports[cfg.DeployPort], cfg.Name)
+			}
+		}
+	}
+
+	for name, problems := range dependencyProblems(configs) {
+		for _, problem := range problems {
+			report.appIssue(name, "%s", problem)
+		}
+	}
+
+	for port, names := range ports {
+		if len(names) < 2 {
+			continue
+		}
+		for _, name := range names {
+			others := make([]string, 0, len(names)-1)
+			for _, other := range names {
+				if other != name {
+					others = append(others, other)
+				}
+			}
+			report.appIssue(name, "port %d is also used by %s", port, strings.Join(others, ", "))
+		}
+	}
+
+	report.Valid = len(report.Errors) == 0 && len(report.Apps) == 0
+	return report
+}
+
+// checkBinary checks that an app's Path is an executable file, resolving
+// bare names through PATH and absolute paths inside Chroot
+func checkBinary(cfg AppConfig) error {
+	if cfg.Path == "" {
+		return fmt.Errorf("path is empty")
+	}
+	if cfg.Shell {
+		return nil // Path is a script for sh, not a binary
+	}
+	path := cfg.binaryPath()
+	if !strings.Contains(path, "/") {
+		if _, err := exec.LookPath(path); err != nil {
+			return fmt.Errorf("path: %s not found in PATH", path)
+		}
+		return nil
+	}
+	if cfg.Chroot != "" {
+		path = filepath.Join(cfg.Chroot, path)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("path: %v", err)
+	}
+	if !st.Mode().IsRegular() {
+		return fmt.Errorf("path: %s is not a regular file", path)
+	}
+	if st.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("path: %s is not executable", path)
+	}
+	return nil
+}
+
+// checkHealthURL checks that a health URL is empty or a usable http(s) or
+// grpc URL
+func checkHealthURL(healthURL string) error {
+	if healthURL == "" {
+		return nil
+	}
+	u, err := url.Parse(healthURL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "grpc":
+	default:
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("no host in %s", healthURL)
+	}
+	return nil
+}
+
+// listenPort returns the port an app serves on: Port, or the explicit port
+// of its health URL. Returns 0 if neither is known.
+func listenPort(cfg AppConfig) int {
+	if cfg.Port > 0 {
+		return cfg.Port
+	}
+	if u, err := url.Parse(cfg.HealthURL); err == nil {
+		if p, err := strconv.Atoi(u.Port()); err == nil {
+			return p
+		}
+	}
+	return 0
+}
+
+// printValidationReport writes a report for people, one problem per line
+func printValidationReport(w io.Writer, report ValidationReport) {
+	for _, e := range report.Errors {
+		fmt.Fprintln(w, e)
+	}
+	names := make([]string, 0, len(report.Apps))
+	for name := range report.Apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, issue := range report.Apps[name] {
+			fmt.Fprintf(w, "%s: %s\n", name, issue)
+		}
+	}
+	if report.Valid {
+		fmt.Fprintln(w, "Config is valid")
+	}
+}
+
+// validateConfigHandler checks the config file albert reloads from, with
+// environment overrides applied, or the running config if there is none
+func validateConfigHandler(mgr *Manager, w http.ResponseWriter, r *http.Request) {
+	var report ValidationReport
+	if mgr.configPath != "" {
+		envConfigs, err := configsFromEnv(os.Environ())
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("invalid app configuration in environment: %v", err))
+		} else {
+			report = validateConfigFile(mgr.configPath, mgr.Profile(), envConfigs)
+		}
+	} else {
+		mgr.mu.RLock()
+		configs := make([]AppConfig, 0, len(mgr.apps))
+		for _, app := range mgr.apps {
+			configs = append(configs, app.Config)
+		}
+		mgr.mu.RUnlock()
+		report = checkConfigs(configs)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Error encoding validation report: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// binaryPath returns where an app's binary is on disk as albert sees it. A
+// relative Path is taken relative to WorkDir, as it is when the app starts;
+// bare names are still looked up in PATH.
+func (cfg AppConfig) binaryPath() string {
+	if cfg.WorkDir == "" || filepath.IsAbs(cfg.Path) || !strings.Contains(cfg.Path, "/") {
+		return cfg.Path
+	}
+	return filepath.Join(cfg.WorkDir, cfg.Path)
+}
+
+// checkWorkDir checks that an app's WorkDir, if set, is a directory
+func checkWorkDir(cfg AppConfig) error {
+	if cfg.WorkDir == "" {
+		return nil
+	}
+	dir := cfg.WorkDir
+	if cfg.Chroot != "" {
+		dir = filepath.Join(cfg.Chroot, dir)
+	}
+	st, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("workdir: %v", err)
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("workdir: %s is not a directory", dir)
+	}
+	return nil
+}